@@ -0,0 +1,347 @@
+// Package anomaly notices checks that are up but slow. A Detector keeps an
+// exponentially weighted mean and variance of the response times reported
+// by webhooks per check and sends a "degraded" notification when a check
+// stays too far above its baseline, and a "recovered" one when it is back
+// to normal. A slowdown that lasts long enough becomes the new baseline.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// Thresholds decide when a response time is anomalous.
+type Thresholds struct {
+	// Alpha is the weight of a new sample in the baseline, between 0 and
+	// 1. Defaults to 0.1.
+	Alpha float64
+
+	// Sigma is how many standard deviations above the mean a response time
+	// has to be to count as slow. Defaults to 3.
+	Sigma float64
+
+	// Absolute is a response time above which a sample always counts as
+	// slow, whatever the baseline. Zero disables it.
+	Absolute time.Duration
+
+	// Consecutive is the number of slow samples in a row after which the
+	// check is degraded. Defaults to 3.
+	Consecutive int
+
+	// MinSamples is the number of samples the baseline needs before Sigma
+	// is applied. Defaults to 20.
+	MinSamples int
+
+	// MinDeviation is the smallest standard deviation Sigma is applied
+	// to, so a very steady check isn't degraded by a millisecond more.
+	// Defaults to 10ms.
+	MinDeviation time.Duration
+
+	// MinRatio is the smallest standard deviation Sigma is applied to, as
+	// a fraction of the mean. Defaults to 0.1.
+	MinRatio float64
+
+	// Adapt is the number of slow samples in a row after which they are
+	// added to the baseline, so a lasting change in response time becomes
+	// the new normal instead of keeping the check degraded. Defaults to
+	// 30.
+	Adapt int
+}
+
+func (t Thresholds) withDefaults() Thresholds {
+	if t.Alpha == 0 {
+		t.Alpha = 0.1
+	}
+	if t.Sigma == 0 {
+		t.Sigma = 3
+	}
+	if t.Consecutive == 0 {
+		t.Consecutive = 3
+	}
+	if t.MinSamples == 0 {
+		t.MinSamples = 20
+	}
+	if t.MinDeviation == 0 {
+		t.MinDeviation = 10 * time.Millisecond
+	}
+	if t.MinRatio == 0 {
+		t.MinRatio = 0.1
+	}
+	if t.Adapt == 0 {
+		t.Adapt = 30
+	}
+	return t
+}
+
+// Baseline is the learned response time of a check.
+type Baseline struct {
+	// Mean is the weighted mean response time in seconds.
+	Mean float64 `json:"mean"`
+
+	// Variance is the weighted variance in seconds squared.
+	Variance float64 `json:"variance"`
+
+	// Samples is the number of samples that went into the baseline.
+	Samples int `json:"samples"`
+
+	// Slow is the number of slow samples in a row.
+	Slow int `json:"slow"`
+
+	// Degraded is true while the check is degraded.
+	Degraded bool `json:"degraded"`
+}
+
+// StdDev returns the standard deviation in seconds.
+func (b Baseline) StdDev() float64 {
+	return math.Sqrt(b.Variance)
+}
+
+// deviation returns the standard deviation Sigma is applied to, in
+// seconds.
+func (t Thresholds) deviation(b *Baseline) float64 {
+	return math.Max(b.StdDev(), math.Max(t.MinDeviation.Seconds(), t.MinRatio*b.Mean))
+}
+
+// Detector learns response time baselines and notifies Next of anomalies.
+type Detector struct {
+	// Next receives the "degraded" and "recovered" notifications.
+	Next notify.Notifier
+
+	// Store persists the baselines. Optional, baselines are kept in
+	// memory only when nil. Baselines are saved by Run and Close, and
+	// right away when a check becomes degraded or recovers.
+	Store Store
+
+	// Default holds the thresholds for checks without their own.
+	Default Thresholds
+
+	// Checks holds thresholds per check id or name.
+	Checks map[string]Thresholds
+
+	mu        sync.Mutex
+	baselines map[string]*Baseline
+	dirty     bool
+
+	// saving serializes saves, so an older snapshot never overwrites a
+	// newer one.
+	saving sync.Mutex
+}
+
+// Load loads the baselines from Store. It is called by the first Observe,
+// but can be called on start up to find out about a broken Store early.
+func (d *Detector) Load() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.load()
+}
+
+// load loads the baselines once. Must be called with d.mu held.
+func (d *Detector) load() error {
+	if d.baselines != nil {
+		return nil
+	}
+
+	baselines := map[string]*Baseline{}
+	if d.Store != nil {
+		saved, err := d.Store.Load()
+		if err != nil {
+			return err
+		}
+		for id, b := range saved {
+			b := b
+			baselines[id] = &b
+		}
+	}
+	d.baselines = baselines
+	return nil
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Detector. Errors are passed to onError, which may
+// be nil.
+func (d *Detector) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = d.Observe(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// Observe adds the response time of hook to the baseline of its check.
+// Webhooks of checks that are down, timed out or carry no response time
+// are ignored. Slow samples are kept out of the baseline until there
+// were Thresholds.Adapt of them in a row, so a short slowdown doesn't
+// become the new normal but a lasting one does.
+func (d *Detector) Observe(ctx context.Context, hook *observery.Webhook) error {
+	if hook.State != "up" || hook.TimedOut || hook.ResponseTime <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	if err := d.load(); err != nil {
+		d.mu.Unlock()
+		return err
+	}
+	var (
+		t     = d.thresholds(hook)
+		x     = hook.ResponseTime.Seconds()
+		b, ok = d.baselines[hook.CheckID]
+	)
+	if !ok {
+		b = &Baseline{}
+		d.baselines[hook.CheckID] = b
+	}
+
+	slow := t.Absolute > 0 && hook.ResponseTime > t.Absolute
+	if b.Samples >= t.MinSamples && x > b.Mean+t.Sigma*t.deviation(b) {
+		slow = true
+	}
+
+	var send *observery.Webhook
+	if slow {
+		b.Slow++
+		if b.Slow > t.Adapt {
+			b.add(x, t.Alpha)
+		}
+		if b.Slow >= t.Consecutive && !b.Degraded {
+			b.Degraded = true
+			send = summary(hook, "degraded", fmt.Sprintf("response time %s, baseline %s ± %s",
+				hook.ResponseTime, seconds(b.Mean), seconds(b.StdDev())))
+		}
+	} else {
+		b.Slow = 0
+		b.add(x, t.Alpha)
+		if b.Degraded {
+			b.Degraded = false
+			send = summary(hook, "recovered", fmt.Sprintf("response time %s, baseline %s ± %s",
+				hook.ResponseTime, seconds(b.Mean), seconds(b.StdDev())))
+		}
+	}
+	d.dirty = true
+	d.mu.Unlock()
+
+	if send == nil {
+		return nil
+	}
+	err := d.Flush()
+	if nerr := d.Next.Notify(ctx, send); nerr != nil && err == nil {
+		err = nerr
+	}
+	return err
+}
+
+// Flush saves the baselines to Store if they changed since the last save.
+// Observe only saves when a check becomes degraded or recovers, so call
+// Flush regularly, or use Run.
+func (d *Detector) Flush() error {
+	d.saving.Lock()
+	defer d.saving.Unlock()
+
+	d.mu.Lock()
+	if d.Store == nil || !d.dirty {
+		d.mu.Unlock()
+		return nil
+	}
+	baselines := make(map[string]Baseline, len(d.baselines))
+	for id, b := range d.baselines {
+		baselines[id] = *b
+	}
+	d.dirty = false
+	d.mu.Unlock()
+
+	if err := d.Store.Save(baselines); err != nil {
+		d.mu.Lock()
+		d.dirty = true
+		d.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run calls Flush every interval until ctx is done. Errors are passed to
+// onError, which may be nil.
+func (d *Detector) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := d.Flush(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Close saves any baselines not saved yet. Call it on shutdown.
+func (d *Detector) Close() error {
+	return d.Flush()
+}
+
+// Baseline returns the baseline of a check.
+func (d *Detector) Baseline(checkID string) (Baseline, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.load(); err != nil {
+		return Baseline{}, false
+	}
+	b, ok := d.baselines[checkID]
+	if !ok {
+		return Baseline{}, false
+	}
+	return *b, true
+}
+
+// Degraded reports whether the check is currently degraded.
+func (d *Detector) Degraded(checkID string) bool {
+	b, ok := d.Baseline(checkID)
+	return ok && b.Degraded
+}
+
+func (d *Detector) thresholds(hook *observery.Webhook) Thresholds {
+	if t, ok := d.Checks[hook.CheckID]; ok {
+		return t.withDefaults()
+	}
+	if t, ok := d.Checks[hook.CheckName]; ok {
+		return t.withDefaults()
+	}
+	return d.Default.withDefaults()
+}
+
+// add updates the exponentially weighted mean and variance with x.
+func (b *Baseline) add(x, alpha float64) {
+	b.Samples++
+	if b.Samples == 1 {
+		b.Mean, b.Variance = x, 0
+		return
+	}
+
+	diff := x - b.Mean
+	incr := alpha * diff
+	b.Mean += incr
+	b.Variance = (1 - alpha) * (b.Variance + diff*incr)
+}
+
+// summary returns a copy of hook with the given state and details.
+func summary(hook *observery.Webhook, state, details string) *observery.Webhook {
+	s := *hook
+	s.State = state
+	s.Details = details
+	return &s
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,180 @@
+package anomaly
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type recorder struct {
+	hooks []observery.Webhook
+}
+
+func (r *recorder) Notify(ctx context.Context, hook *observery.Webhook) error {
+	r.hooks = append(r.hooks, *hook)
+	return nil
+}
+
+func TestDetector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anomaly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &FileStore{Path: filepath.Join(dir, "baselines.json")}
+
+	rec := &recorder{}
+	d := &Detector{Next: rec, Store: store, Default: Thresholds{MinSamples: 10, Consecutive: 2}}
+
+	ctx := context.Background()
+	observe := func(d *Detector, ms int) {
+		hook := &observery.Webhook{CheckID: "1", CheckName: "api", State: "up", ResponseTime: time.Duration(ms) * time.Millisecond}
+		if err := d.Observe(ctx, hook); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		observe(d, 100+i%3*10)
+	}
+	observe(d, 1000)
+	if len(rec.hooks) != 0 {
+		t.Fatalf("Expected no notification after a single slow sample but got %+v\n", rec.hooks)
+	}
+
+	// A restarted detector picks up the baseline and the slow streak.
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d = &Detector{Next: rec, Store: store, Default: Thresholds{MinSamples: 10, Consecutive: 2}}
+	if err := d.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := d.Baseline("1"); !ok || b.Samples != 20 || b.Slow != 1 {
+		t.Fatalf("Expected the saved baseline but got %+v\n", b)
+	}
+
+	observe(d, 1000)
+	observe(d, 1000)
+	if len(rec.hooks) != 1 || rec.hooks[0].State != "degraded" || !d.Degraded("1") {
+		t.Fatalf("Expected a single degraded notification but got %+v\n", rec.hooks)
+	}
+
+	observe(d, 105)
+	if len(rec.hooks) != 2 || rec.hooks[1].State != "recovered" {
+		t.Fatalf("Expected a recovered notification but got %+v\n", rec.hooks)
+	}
+	if b, _ := d.Baseline("1"); b.Mean > 0.2 {
+		t.Fatalf("Expected slow samples to be kept out of the baseline but got %+v\n", b)
+	}
+}
+
+// countingStore counts the saves.
+type countingStore struct {
+	saves     int
+	baselines map[string]Baseline
+}
+
+func (s *countingStore) Load() (map[string]Baseline, error) {
+	return s.baselines, nil
+}
+
+func (s *countingStore) Save(baselines map[string]Baseline) error {
+	s.saves++
+	s.baselines = baselines
+	return nil
+}
+
+func TestFlush(t *testing.T) {
+	store := &countingStore{}
+	d := &Detector{Next: &recorder{}, Store: store, Default: Thresholds{MinSamples: 10, Consecutive: 2}}
+
+	observe := func(ms int) {
+		hook := &observery.Webhook{CheckID: "1", State: "up", ResponseTime: time.Duration(ms) * time.Millisecond}
+		if err := d.Observe(context.Background(), hook); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		observe(100)
+	}
+	if store.saves != 0 {
+		t.Fatalf("Expected samples not to be saved one by one but got %d saves\n", store.saves)
+	}
+
+	if err := d.Flush(); err != nil || store.saves != 1 || store.baselines["1"].Samples != 20 {
+		t.Fatalf("Expected a single save of 20 samples but got %d saves of %+v: %v\n", store.saves, store.baselines, err)
+	}
+	if err := d.Close(); err != nil || store.saves != 1 {
+		t.Fatalf("Expected nothing to save but got %d saves: %v\n", store.saves, err)
+	}
+
+	observe(1000)
+	observe(1000)
+	if store.saves != 2 || !store.baselines["1"].Degraded {
+		t.Fatalf("Expected the degraded check to be saved right away but got %d saves of %+v\n", store.saves, store.baselines)
+	}
+}
+
+func TestAbsolute(t *testing.T) {
+	rec := &recorder{}
+	d := &Detector{Next: rec, Checks: map[string]Thresholds{"api": {Absolute: time.Second, Consecutive: 1}}}
+
+	d.Observe(context.Background(), &observery.Webhook{CheckID: "1", CheckName: "api", State: "up", ResponseTime: 2 * time.Second})
+	if len(rec.hooks) != 1 || rec.hooks[0].State != "degraded" {
+		t.Fatalf("Expected the absolute threshold to apply without a baseline but got %+v\n", rec.hooks)
+	}
+}
+
+func TestMinDeviation(t *testing.T) {
+	rec := &recorder{}
+	d := &Detector{Next: rec, Default: Thresholds{MinSamples: 10, Consecutive: 1}}
+
+	observe := func(rt time.Duration) {
+		d.Observe(context.Background(), &observery.Webhook{CheckID: "1", State: "up", ResponseTime: rt})
+	}
+	for i := 0; i < 20; i++ {
+		observe(80 * time.Millisecond)
+	}
+
+	observe(81 * time.Millisecond)
+	observe(100 * time.Millisecond)
+	if len(rec.hooks) != 0 {
+		t.Fatalf("Expected small increases over a steady baseline to be ignored but got %+v\n", rec.hooks)
+	}
+	observe(200 * time.Millisecond)
+	if len(rec.hooks) != 1 || rec.hooks[0].State != "degraded" {
+		t.Fatalf("Expected a large increase to degrade the check but got %+v\n", rec.hooks)
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	rec := &recorder{}
+	d := &Detector{Next: rec, Default: Thresholds{MinSamples: 10, Consecutive: 2, Adapt: 5}}
+
+	observe := func(rt time.Duration) {
+		d.Observe(context.Background(), &observery.Webhook{CheckID: "1", State: "up", ResponseTime: rt})
+	}
+	for i := 0; i < 20; i++ {
+		observe(100 * time.Millisecond)
+	}
+
+	// Response times move to a new normal for good.
+	for i := 0; i < 100 && len(rec.hooks) < 2; i++ {
+		observe(500 * time.Millisecond)
+	}
+	if len(rec.hooks) != 2 || rec.hooks[0].State != "degraded" || rec.hooks[1].State != "recovered" {
+		t.Fatalf("Expected the check to recover once the slowdown became the baseline but got %+v\n", rec.hooks)
+	}
+	for i := 0; i < 30; i++ {
+		observe(500 * time.Millisecond)
+	}
+	if b, _ := d.Baseline("1"); len(rec.hooks) != 2 || b.Mean < 0.4 {
+		t.Fatalf("Expected the baseline to adapt but got %+v and %+v\n", b, rec.hooks)
+	}
+}
@@ -0,0 +1,48 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/sfreiberg/observery/internal/atomicfile"
+)
+
+// Store persists baselines so learning survives restarts.
+type Store interface {
+	// Load returns the saved baselines.
+	Load() (map[string]Baseline, error)
+
+	// Save replaces the saved baselines.
+	Save(baselines map[string]Baseline) error
+}
+
+// FileStore is a Store that keeps baselines in a JSON file.
+type FileStore struct {
+	// Path of the file.
+	Path string
+}
+
+// Load reads the baselines from the file. A missing file holds no baselines.
+func (s *FileStore) Load() (map[string]Baseline, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var baselines map[string]Baseline
+	err = json.Unmarshal(data, &baselines)
+	return baselines, err
+}
+
+// Save atomically replaces the file with the baselines.
+func (s *FileStore) Save(baselines map[string]Baseline) error {
+	data, err := json.Marshal(baselines)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0600)
+}
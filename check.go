@@ -2,6 +2,9 @@ package observery
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -14,32 +17,39 @@ type ListChecksResponse struct {
 	Reason string
 
 	// Checks is a list of all checks.
-	Checks []struct {
-		// ID of the check.
-		ID string
+	Checks []Check
+}
 
-		// Name of the check.
-		Name string
+// Check holds the summary of a check as returned by Client.ListChecks.
+type Check struct {
+	// ID of the check.
+	ID string
 
-		// Active
-		Active bool
+	// Name of the check.
+	Name string
 
-		// Type will be one of: http, ping, ssh, ftp, pop, smtp, imap or cert.
-		Type string
+	// Active
+	Active bool
 
-		// State is the current state of the check. Possible states are:
-		// up, down or waiting.
-		State string
+	// Type will be one of: http, ping, ssh, ftp, pop, smtp, imap or cert.
+	Type string
 
-		// Since holds the time of the last state change.
-		Since time.Time
+	// State is the current state of the check. Possible states are:
+	// up, down or waiting.
+	State string
 
-		// URL is the url to check for type http.
-		URL string
+	// Since holds the time of the last state change.
+	Since time.Time
 
-		// Host holds the host for ping, ssh, ftp, pop, smtp, imap and cert.
-		Host string
-	}
+	// URL is the url to check for type http.
+	URL string
+
+	// Host holds the host for ping, ssh, ftp, pop, smtp, imap and cert.
+	Host string
+
+	// Account is the name of the account the check belongs to when it was
+	// listed through a MultiClient. Empty otherwise.
+	Account string `json:",omitempty"`
 }
 
 // GetCheckResponse is the response when calling Client.GetCheck.
@@ -293,17 +303,6 @@ func (c *Client) ListChecks(ctx context.Context) (*ListChecksResponse, error) {
 		} `json:"result"`
 	}{}
 
-	type Check struct {
-		ID     string
-		Name   string
-		Active bool
-		Type   string
-		State  string
-		Since  time.Time
-		URL    string
-		Host   string
-	}
-
 	err := c.get(ctx, url, nil, s)
 
 	resp := &ListChecksResponse{Success: s.Success, Reason: s.Reason}
@@ -361,3 +360,111 @@ func (c *Client) DeleteCheck(ctx context.Context, id string) (*DeleteCheckRespon
 	err := c.delete(ctx, url, nil, resp)
 	return resp, err
 }
+
+// ValidationError describes a single invalid field found by
+// CreateCheckRequest.Validate.
+type ValidationError struct {
+	// Field is the name of the invalid field as sent to the API.
+	Field string
+
+	// Message explains why the field is invalid.
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors holds every invalid field found by
+// CreateCheckRequest.Validate.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the request for mistakes the API would reject, or that
+// would make the check fail, without calling the API. It returns
+// ValidationErrors when anything is wrong.
+func (req *CreateCheckRequest) Validate() error {
+	var errs ValidationErrors
+	invalid := func(field, msg string) {
+		errs = append(errs, ValidationError{Field: field, Message: msg})
+	}
+
+	switch req.Type {
+	case "http", "ping", "ssh", "ftp", "pop", "smtp", "imap", "cert":
+	default:
+		invalid("type", "must be one of http, ping, ssh, ftp, pop, smtp, imap or cert")
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		invalid("name", "is required")
+	}
+
+	if req.Interval < 1 {
+		invalid("interval", "must be at least 1 minute")
+	}
+
+	if req.Type == "http" {
+		if req.URL == nil || *req.URL == "" {
+			invalid("url", "is required for http checks")
+		} else if u, err := url.Parse(*req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("url", "must be an absolute http or https url")
+		}
+	} else {
+		if req.Type != "" && (req.Host == nil || *req.Host == "") {
+			invalid("host", "is required for "+req.Type+" checks")
+		}
+		if req.URL != nil {
+			invalid("url", "is only valid for http checks")
+		}
+		if req.SendData != nil {
+			invalid("sendData", "is only valid for http checks")
+		}
+		if req.HTTPHeaders != nil {
+			invalid("httpHeaders", "is only valid for http checks")
+		}
+	}
+
+	if req.HTTPHeaders != nil {
+		for _, line := range strings.Split(*req.HTTPHeaders, "\n") {
+			if line = strings.TrimSpace(line); line != "" && strings.Index(line, ":") < 1 {
+				invalid("httpHeaders", fmt.Sprintf("%q must be formatted as 'key: value'", line))
+			}
+		}
+	}
+
+	if (req.Username != nil || req.Password != nil) && req.Type != "http" && req.Type != "ftp" {
+		invalid("username", "is only valid for http and ftp checks")
+	}
+
+	if req.Port != nil && (*req.Port < 1 || *req.Port > 65535) {
+		invalid("port", "must be between 1 and 65535")
+	}
+
+	if req.Secure != nil && *req.Secure {
+		switch req.Type {
+		case "ftp", "pop", "smtp", "imap":
+		default:
+			invalid("secure", "is only valid for ftp, pop, smtp and imap checks")
+		}
+	}
+
+	if req.Type == "cert" {
+		if req.CertExpirationDays == nil {
+			invalid("certExpirationDays", "is required for cert checks")
+		} else if *req.CertExpirationDays < 1 {
+			invalid("certExpirationDays", "must be at least 1")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
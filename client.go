@@ -14,21 +14,36 @@ const (
 
 // Client is the main entry point into the observery API and its endpoints.
 type Client struct {
-	username string
-	password string
-	client   *http.Client
+	creds  CredentialsProvider
+	client *http.Client
 }
 
 // NewClient creates a new client with appropriate API keys.
 func NewClient(username, password string) *Client {
+	return NewClientWithProvider(StaticProvider{Username: username, Password: password})
+}
+
+// NewClientWithProvider creates a new client that asks p for credentials
+// before every request.
+func NewClientWithProvider(p CredentialsProvider) *Client {
 	c := &Client{
-		username: username,
-		password: password,
-		client:   &http.Client{},
+		creds:  p,
+		client: &http.Client{},
 	}
 	return c
 }
 
+// NewDefaultClient creates a new client that looks for credentials with
+// DefaultChain. It returns ErrNoCredentials if none of the providers has
+// any, so misconfiguration shows up before the first request.
+func NewDefaultClient() (*Client, error) {
+	chain := DefaultChain()
+	if _, err := chain.Credentials(); err != nil {
+		return nil, err
+	}
+	return NewClientWithProvider(chain), nil
+}
+
 func (c *Client) get(ctx context.Context, url string, input, output interface{}) error {
 	return c.exec(ctx, url, "GET", input, output)
 }
@@ -71,7 +86,11 @@ func (c *Client) exec(ctx context.Context, u, method string, input, output inter
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	req.SetBasicAuth(c.username, c.password)
+	creds, err := c.creds.Credentials()
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -0,0 +1,83 @@
+// Command observery-exporter serves observery check and outage information
+// as Prometheus metrics. It also accepts observery webhooks and serves the
+// response times, status codes and timeouts they carry as a separate set of
+// metrics. Error budgets and burn rates of SLOs are served when given an SLO
+// configuration.
+//
+// Credentials are looked up with observery.DefaultChain: environment
+// variables, mounted secret files, the config file profile or netrc. They
+// are re-read on every API request, so rotated secrets are picked up
+// without a restart.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/exporter"
+	"github.com/sfreiberg/observery/slo"
+	"github.com/sfreiberg/observery/store"
+)
+
+func main() {
+	var (
+		listen = flag.String("listen", ":9710", "address to serve metrics on")
+		path   = flag.String("path", "/metrics", "path to serve metrics on")
+		cache  = flag.Duration("cache", 0, "how long to cache API results (default 1m)")
+
+		accounts = flag.String("accounts", "", "comma-separated config file profiles to export together, labeled by account")
+
+		sloConfig = flag.String("slo", "", "YAML file of SLOs to serve error budget and burn rate metrics for")
+		history   = flag.String("store", "", "outage history file SLOs are computed from (default the most recent outages)")
+
+		webhookPath        = flag.String("webhook-path", "/webhook", "path observery webhooks are sent to")
+		webhookMetricsPath = flag.String("webhook-metrics-path", "/metrics/webhook", "path to serve webhook metrics on")
+		maxChecks          = flag.Int("webhook-max-checks", 0, "maximum number of checks labeled individually in webhook metrics (0 for no limit)")
+	)
+	flag.Parse()
+
+	var client exporter.Lister
+	if *accounts != "" {
+		multi, err := observery.NewMultiClientFromProfiles("", strings.Split(*accounts, ","))
+		if err != nil {
+			log.Fatalf("Unable to find credentials: %s", err)
+		}
+		client = multi
+	} else {
+		single, err := observery.NewDefaultClient()
+		if err != nil {
+			log.Fatalf("Unable to find credentials: %s", err)
+		}
+		client = single
+	}
+
+	e := exporter.New(client)
+	if *cache > 0 {
+		e.CacheTTL = *cache
+	}
+	if *sloConfig != "" {
+		config, err := slo.LoadConfig(*sloConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		e.Objectives = config.Objectives
+	}
+	if *history != "" {
+		s, err := store.Open(*history)
+		if err != nil {
+			log.Fatal(err)
+		}
+		e.History = s
+	}
+
+	m := &exporter.WebhookMetrics{MaxChecks: *maxChecks}
+
+	http.Handle(*path, e)
+	http.Handle(*webhookMetricsPath, m)
+	http.HandleFunc(*webhookPath, m.Handler())
+	log.Printf("Serving metrics on %s%s", *listen, *path)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
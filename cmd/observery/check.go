@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/probe"
+)
+
+func createCheck(ctx context.Context, args []string) error {
+	var (
+		fs  = flag.NewFlagSet("create-check", flag.ContinueOnError)
+		req = &observery.CreateCheckRequest{}
+
+		dryRun   = fs.Bool("dry-run", false, "validate and run the check locally without creating it")
+		url      = fs.String("url", "", "url to check for http checks")
+		username = fs.String("username", "", "username for http or ftp checks")
+		password = fs.String("password", "", "password for http or ftp checks")
+		sendData = fs.String("send-data", "", "data to post for http checks")
+		headers  = fs.String("headers", "", "http headers, one 'key: value' per line")
+		host     = fs.String("host", "", "host to check for all other types")
+		port     = fs.Int("port", 0, "port to check")
+		secure   = fs.Bool("secure", false, "use the secure version of the protocol for ftp, pop, smtp and imap")
+		certDays = fs.Int("cert-days", 0, "days until certificate expiration that should result in down status")
+	)
+	fs.StringVar(&req.Type, "type", "http", "check type: http, ping, ssh, ftp, pop, smtp, imap or cert")
+	fs.StringVar(&req.Name, "name", "", "name of the check")
+	fs.BoolVar(&req.Active, "active", true, "whether the check is active")
+	fs.IntVar(&req.Interval, "interval", 5, "how often to run the check in minutes")
+	fs.StringVar(&req.Contacts, "contacts", "", "comma-separated list of contact ids")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Only send the optional fields that were given on the command line.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "url":
+			req.URL = url
+		case "username":
+			req.Username = username
+		case "password":
+			req.Password = password
+		case "send-data":
+			req.SendData = sendData
+		case "headers":
+			req.HTTPHeaders = headers
+		case "host":
+			req.Host = host
+		case "port":
+			req.Port = port
+		case "secure":
+			req.Secure = secure
+		case "cert-days":
+			req.CertExpirationDays = certDays
+		}
+	})
+
+	if *dryRun {
+		return testCheck(ctx, req)
+	}
+
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.CreateCheck(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		for _, reason := range resp.Reasons {
+			fmt.Printf("%s: %s\n", reason.Field, reason.Error)
+		}
+		return errors.New(resp.Reason)
+	}
+
+	fmt.Println(resp.Result.ID)
+	return nil
+}
+
+func testCheck(ctx context.Context, req *observery.CreateCheckRequest) error {
+	result := probe.TestCheck(ctx, req)
+
+	if !result.Valid {
+		return result.Err
+	}
+	if result.Err != nil {
+		return fmt.Errorf("unable to resolve host: %s", result.Err)
+	}
+
+	fmt.Printf("Resolved:  %v\n", result.Addresses)
+	fmt.Printf("State:     %s\n", result.Result.State)
+	if result.Result.HTTPStatusCode != 0 {
+		fmt.Printf("Status:    %d\n", result.Result.HTTPStatusCode)
+	}
+	fmt.Printf("Response:  %s\n", result.Result.ResponseTime)
+	if result.Result.TimedOut {
+		fmt.Printf("Timed out: true\n")
+	}
+	if result.CertDaysRemaining != nil {
+		fmt.Printf("Cert:      expires in %d days\n", *result.CertDaysRemaining)
+	}
+	if result.Result.Details != "" {
+		fmt.Printf("Details:   %s\n", result.Result.Details)
+	}
+
+	if !result.Passed() {
+		return errors.New("check would be down")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/dashboard"
+)
+
+// serveDashboard serves the web dashboard. Basic auth is enabled by setting
+// the OBSERVERY_DASHBOARD_USERNAME and OBSERVERY_DASHBOARD_PASSWORD
+// environment variables.
+func serveDashboard(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("serve-dashboard", flag.ContinueOnError)
+
+		listen      = fs.String("listen", ":8080", "address to serve the dashboard on")
+		interval    = fs.Duration("interval", time.Minute, "how often to poll for the live feed (0 to only use webhooks)")
+		cache       = fs.Duration("cache", time.Minute, "how long to cache API results between page loads")
+		webhookPath = fs.String("webhook-path", "", "also accept observery webhooks for the live feed on this path")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	d := dashboard.New(client)
+	d.CacheTTL = *cache
+	d.Username = os.Getenv("OBSERVERY_DASHBOARD_USERNAME")
+	d.Password = os.Getenv("OBSERVERY_DASHBOARD_PASSWORD")
+
+	if *interval > 0 {
+		go d.Watch(ctx, client, *interval, &observery.WatchOptions{
+			OnError: func(err error) {
+				log.Printf("Unable to poll checks: %s", err)
+			},
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", d)
+	if *webhookPath != "" {
+		mux.HandleFunc(*webhookPath, d.WebhookHandler())
+	}
+
+	log.Printf("Serving the dashboard on %s", *listen)
+	return http.ListenAndServe(*listen, mux)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/sfreiberg/observery/dependency"
+)
+
+func dependencies(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("deps", flag.ContinueOnError)
+
+		config = fs.String("config", "", "YAML file declaring the check dependencies")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *config == "" {
+		return errors.New("-config is required")
+	}
+
+	g, err := dependency.LoadGraph(*config)
+	if err != nil {
+		return err
+	}
+	return g.WriteDOT(os.Stdout)
+}
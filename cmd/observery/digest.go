@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sfreiberg/observery/digest"
+)
+
+// sendDigest sends an uptime digest. It is meant to be run from cron or
+// another scheduler, with -last keeping the previous digest between runs.
+// SMTP credentials are read from the OBSERVERY_SMTP_USERNAME and
+// OBSERVERY_SMTP_PASSWORD environment variables.
+func sendDigest(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("digest", flag.ContinueOnError)
+
+		config = fs.String("config", "", "YAML file with the subject, period and check groups")
+		path   = fs.String("store", "", "compute from this outage history file instead of the API")
+		last   = fs.String("last", "", "file keeping the previous digest, to report changes")
+		server = fs.String("smtp", "localhost:25", "SMTP server host:port")
+		from   = fs.String("from", "", "sender address")
+		to     = fs.String("to", "", "comma-separated recipient addresses")
+		text   = fs.String("text-template", "", "file with a text/template for the plain text part")
+		html   = fs.String("html-template", "", "file with an html/template for the HTML part")
+		stdout = fs.Bool("print", false, "write the email to stdout instead of sending it")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conf := &digest.Config{}
+	if *config != "" {
+		var err error
+		if conf, err = digest.LoadConfig(*config); err != nil {
+			return err
+		}
+	}
+
+	m := &digest.Mailer{
+		Addr:     *server,
+		Username: os.Getenv("OBSERVERY_SMTP_USERNAME"),
+		Password: os.Getenv("OBSERVERY_SMTP_PASSWORD"),
+		From:     *from,
+	}
+	if *to != "" {
+		m.To = strings.Split(*to, ",")
+	}
+	for _, t := range []struct {
+		file string
+		dst  *string
+	}{
+		{*text, &m.Templates.Text},
+		{*html, &m.Templates.HTML},
+	} {
+		if t.file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(t.file)
+		if err != nil {
+			return err
+		}
+		*t.dst = string(data)
+	}
+
+	client, err := newLister()
+	if err != nil {
+		return err
+	}
+	resp, err := client.ListChecks(ctx)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Reason)
+	}
+	warnPartial(resp.Reason)
+
+	outages, err := loadOutages(ctx, *path, "", "")
+	if err != nil {
+		return err
+	}
+
+	var prev *digest.Digest
+	if *last != "" {
+		if prev, err = digest.Load(*last); err != nil {
+			return err
+		}
+	}
+
+	d := digest.Generate(resp.Checks, outages, conf, prev, time.Now())
+
+	if *stdout {
+		msg, err := m.Message(d)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(msg)
+		return err
+	}
+
+	if err := m.Send(d); err != nil {
+		return err
+	}
+	if *last != "" {
+		return d.Save(*last)
+	}
+	return nil
+}
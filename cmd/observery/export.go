@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/export"
+	"github.com/sfreiberg/observery/store"
+)
+
+func exportOutages(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("export", flag.ContinueOnError)
+
+		format  = fs.String("format", "csv", "output format: csv, jsonl or ics")
+		columns = fs.String("columns", strings.Join(export.Columns, ","), "comma-separated csv columns")
+		tz      = fs.String("tz", "UTC", "time zone for csv and jsonl times")
+		out     = fs.String("out", "", "file to write to (default stdout)")
+		path    = fs.String("store", "", "export from this outage history file instead of the API")
+		from    = fs.String("from", "", "only export outages after this date or time")
+		to      = fs.String("to", "", "only export outages before this date or time")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+	opts := &export.Options{
+		Columns:  strings.Split(*columns, ","),
+		Location: loc,
+	}
+
+	outages, err := loadOutages(ctx, *path, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		return export.WriteCSV(w, outages, opts)
+	case "jsonl":
+		return export.WriteJSONLines(w, outages, opts)
+	case "ics":
+		return export.WriteICal(w, outages, opts)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
+
+// loadOutages returns the outages between from and to, either from the
+// store at path or, when path is empty, the most recent outages from the
+// API.
+func loadOutages(ctx context.Context, path, from, to string) ([]observery.Outage, error) {
+	start, err := parseTime(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime(to)
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		s, err := store.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return s.Range(start, end)
+	}
+
+	client, err := newLister()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListOutages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, errors.New(resp.Reason)
+	}
+	warnPartial(resp.Reason)
+	return store.Filter(resp.Outages, start, end), nil
+}
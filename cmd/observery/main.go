@@ -0,0 +1,129 @@
+// Command observery is a command line interface to the observery API.
+//
+// Credentials are read from the OBSERVERY_USERNAME and OBSERVERY_PASSWORD
+// environment variables, the files named by OBSERVERY_USERNAME_FILE and
+// OBSERVERY_PASSWORD_FILE, the profile named by OBSERVERY_PROFILE (default
+// "default") in ~/.config/observery/config, or ~/.netrc, in that order.
+//
+// Usage:
+//
+//	observery [-accounts a,b] <command> [flags]
+//
+// With -accounts, the commands that list checks and outages (digest,
+// export, slo, statuspage, sync and top) combine the named profiles of the
+// config file into one view. Ids are then qualified as "account/id".
+//
+// Run a command with -h to see its flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sfreiberg/observery"
+)
+
+// command is a single subcommand of the CLI.
+type command struct {
+	// summary is a one line description shown in the usage.
+	summary string
+
+	// run executes the command with the arguments after the command name.
+	run func(ctx context.Context, args []string) error
+}
+
+var accounts = flag.String("accounts", "", "comma-separated config file profiles to combine into one view")
+
+var commands = map[string]command{
+	"create-check":    {"create a check, or test it locally with -dry-run", createCheck},
+	"deps":            {"validate check dependencies and print them as DOT", dependencies},
+	"digest":          {"email an uptime digest over SMTP", sendDigest},
+	"export":          {"export outages as csv, json lines or icalendar", exportOutages},
+	"report":          {"generate an incident report for an outage", incidentReport},
+	"serve-dashboard": {"serve a live web dashboard", serveDashboard},
+	"slo":             {"show the error budgets of SLOs", serviceLevels},
+	"statuspage":      {"render a static status page", statusPage},
+	"sync":            {"sync outages into a local history file", syncOutages},
+	"top":             {"show live check status in the terminal", topCommand},
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(context.Background(), flag.Args()[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "observery %s: %s\n", flag.Arg(0), err)
+		}
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: observery [-accounts a,b] <command> [flags]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].summary)
+	}
+}
+
+// newClient creates a client with the default credentials chain.
+func newClient() (*observery.Client, error) {
+	client, err := observery.NewDefaultClient()
+	if err == observery.ErrNoCredentials {
+		return nil, errors.New("no credentials found, set OBSERVERY_USERNAME and OBSERVERY_PASSWORD or add a profile to ~/.config/observery/config")
+	}
+	return client, err
+}
+
+// lister is the part of the API used by commands that also work across
+// accounts.
+type lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+	GetCheck(ctx context.Context, id string) (*observery.GetCheckResponse, error)
+	GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error)
+	UpdateCheck(ctx context.Context, req *observery.UpdateCheckRequest) (*observery.UpdateCheckResponse, error)
+}
+
+// newLister creates a MultiClient for the profiles given with -accounts,
+// or a single client otherwise.
+func newLister() (lister, error) {
+	if *accounts == "" {
+		client, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+
+	multi, err := observery.NewMultiClientFromProfiles("", strings.Split(*accounts, ","))
+	if err != nil {
+		return nil, err
+	}
+	return multi, nil
+}
+
+// warnPartial prints the accounts that failed when a MultiClient returned
+// partial results.
+func warnPartial(reason string) {
+	if reason != "" {
+		fmt.Fprintf(os.Stderr, "warning: partial results: %s\n", reason)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sfreiberg/observery/report"
+)
+
+func incidentReport(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("report", flag.ContinueOnError)
+
+		outage = fs.String("outage", "", "id of the outage to report on")
+		format = fs.String("format", "markdown", "output format: markdown or html")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outage == "" {
+		return errors.New("-outage is required")
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	r, err := report.Generate(ctx, client, *outage)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "markdown":
+		return report.WriteMarkdown(os.Stdout, r)
+	case "html":
+		return report.WriteHTML(os.Stdout, r)
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
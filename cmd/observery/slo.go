@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sfreiberg/observery/slo"
+)
+
+func serviceLevels(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("slo", flag.ContinueOnError)
+
+		config = fs.String("config", "", "YAML file defining the SLOs")
+		path   = fs.String("store", "", "compute from this outage history file instead of the API")
+		format = fs.String("format", "text", "output format: text or json")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *config == "" {
+		return errors.New("-config is required")
+	}
+
+	c, err := slo.LoadConfig(*config)
+	if err != nil {
+		return err
+	}
+
+	outages, err := loadOutages(ctx, *path, "", "")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	budgets := make([]slo.Budget, len(c.Objectives))
+	for i, o := range c.Objectives {
+		budgets[i] = slo.Compute(o, outages, now)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(budgets)
+	case "text":
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OBJECTIVE\tTARGET\tWINDOW\tAVAILABILITY\tSPENT\tREMAINING\t1H BURN")
+	for i, b := range budgets {
+		fmt.Fprintf(w, "%s\t%g%%\t%s\t%.3f%%\t%s\t%.1f%%\t%.1fx\n",
+			b.Objective,
+			b.Target,
+			c.Objectives[i].Window,
+			b.Availability,
+			b.Spent.Round(time.Second),
+			100*b.RemainingRatio,
+			slo.BurnRate(c.Objectives[i], outages, time.Hour, now),
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"strings"
+
+	"github.com/sfreiberg/observery/statuspage"
+	"github.com/sfreiberg/observery/store"
+)
+
+func statusPage(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("statuspage", flag.ContinueOnError)
+
+		config   = fs.String("config", "", "YAML config file with groups, display names and hidden checks")
+		history  = fs.String("store", "", "outage history file used for the uptime bars (default the most recent outages)")
+		out      = fs.String("out", "public", "directory to write index.html, status.json and feed.atom to")
+		snapshot = fs.String("snapshot", "", "render from this snapshot file instead of the API")
+		save     = fs.String("save-snapshot", "", "also save the fetched data as a snapshot file")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conf := &statuspage.Config{}
+	if *config != "" {
+		var err error
+		if conf, err = statuspage.LoadConfig(*config); err != nil {
+			return err
+		}
+	}
+
+	snap := &statuspage.Snapshot{}
+	if *snapshot != "" {
+		data, err := ioutil.ReadFile(*snapshot)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, snap); err != nil {
+			return err
+		}
+	} else {
+		client, err := newLister()
+		if err != nil {
+			return err
+		}
+		if snap, err = statuspage.Fetch(ctx, client); err != nil {
+			return err
+		}
+		if len(snap.Unavailable) > 0 {
+			warnPartial("unavailable accounts " + strings.Join(snap.Unavailable, ", "))
+		}
+	}
+
+	if *history != "" {
+		s, err := store.Open(*history)
+		if err != nil {
+			return err
+		}
+		if err := snap.AddHistory(s, conf); err != nil {
+			return err
+		}
+	}
+
+	if *save != "" {
+		data, err := json.Marshal(snap)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*save, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return statuspage.WriteDir(*out, statuspage.Build(snap, conf))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/sfreiberg/observery/store"
+)
+
+func syncOutages(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("sync", flag.ContinueOnError)
+
+		path     = fs.String("store", "outages.json", "file to keep the outage history in")
+		interval = fs.Duration("interval", 0, "keep syncing at this interval instead of syncing once")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := store.Open(*path)
+	if err != nil {
+		return err
+	}
+
+	client, err := newLister()
+	if err != nil {
+		return err
+	}
+
+	if *interval == 0 {
+		return store.Sync(ctx, s, client)
+	}
+
+	store.Run(ctx, s, client, *interval, func(err error) {
+		log.Printf("Unable to sync outages: %s", err)
+	})
+	return nil
+}
+
+// parseTime parses an optional RFC 3339 time or YYYY-MM-DD date flag.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Escape sequences understood by every VT100 compatible terminal, which
+// includes whatever is on the other end of an SSH session.
+const (
+	altScreen  = "\x1b[?1049h"
+	mainScreen = "\x1b[?1049l"
+	hideCursor = "\x1b[?25l"
+	showCursor = "\x1b[?25h"
+	clearHome  = "\x1b[H\x1b[2J"
+	clearLine  = "\x1b[K"
+	reverse    = "\x1b[7m"
+	bold       = "\x1b[1m"
+	red        = "\x1b[31m"
+	green      = "\x1b[32m"
+	yellow     = "\x1b[33m"
+	dim        = "\x1b[2m"
+	reset      = "\x1b[0m"
+)
+
+// Keys that don't map to a single printable byte.
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyEnter = "enter"
+	keyEsc   = "esc"
+	keyBack  = "backspace"
+)
+
+// terminal puts the controlling terminal into character mode with stty,
+// which is available wherever a shell is, and restores it on close.
+type terminal struct {
+	saved string
+	keys  chan string
+}
+
+func openTerminal() (*terminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read terminal settings, is stdin a terminal? %s", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+
+	t := &terminal{saved: strings.TrimSpace(saved), keys: make(chan string)}
+	fmt.Print(altScreen + hideCursor)
+	go t.read()
+	return t, nil
+}
+
+func (t *terminal) close() {
+	fmt.Print(showCursor + mainScreen)
+	stty(t.saved)
+}
+
+// size returns the number of rows and columns, defaulting to 24x80.
+func (t *terminal) size() (int, int) {
+	out, err := stty("size")
+	if err == nil {
+		var rows, cols int
+		if n, _ := fmt.Sscan(out, &rows, &cols); n == 2 && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// read turns stdin into key names. Arrow keys arrive as ESC [ A and ESC [ B.
+func (t *terminal) read() {
+	r := bufio.NewReader(os.Stdin)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			close(t.keys)
+			return
+		}
+
+		switch b {
+		case '\r', '\n':
+			t.keys <- keyEnter
+		case 127, '\b':
+			t.keys <- keyBack
+		case 0x1b:
+			if r.Buffered() >= 2 {
+				seq := make([]byte, 2)
+				r.Read(seq)
+				switch string(seq) {
+				case "[A":
+					t.keys <- keyUp
+				case "[B":
+					t.keys <- keyDown
+				}
+				continue
+			}
+			t.keys <- keyEsc
+		default:
+			t.keys <- string(b)
+		}
+	}
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
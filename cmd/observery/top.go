@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// sortOrders are the orders the list can be cycled through with "s".
+var sortOrders = []string{"state", "since", "name"}
+
+// maintenanceMessage is shown for "m", since the API can't toggle
+// maintenance mode.
+const maintenanceMessage = "Maintenance mode can't be changed through the observery API, use observery.com"
+
+func validSort(order string) bool {
+	for _, o := range sortOrders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+// topView is the state of the observery top screen.
+type topView struct {
+	client lister
+
+	selector observery.Selector
+	sortBy   string
+
+	all     []observery.Check
+	checks  []observery.Check
+	outages []observery.Outage
+	fetched time.Time
+	err     error
+	cursor  int
+
+	// detail is set while a single check is shown.
+	detail *observery.GetCheckResponse
+
+	// input holds the selector being typed after "/".
+	input *string
+
+	// confirm is the question asked before action runs.
+	confirm string
+	action  func(ctx context.Context) error
+
+	message string
+}
+
+// topCommand shows the checks like top(1), refreshing every interval. A check
+// can be opened for details and activated or deactivated. The observery API
+// has no way to change maintenance mode, so it is only shown, and "m"
+// explains that.
+func topCommand(ctx context.Context, args []string) error {
+	var (
+		fs = flag.NewFlagSet("top", flag.ContinueOnError)
+
+		interval = fs.Duration("interval", 30*time.Second, "how often to refresh")
+		selector = fs.String("selector", "", `only show checks matching a selector like "type=http,state=down"`)
+		sortBy   = fs.String("sort", "state", "sort order: state, since or name")
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage of top:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nMaintenance mode can't be changed through the observery API, use observery.com instead.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !validSort(*sortBy) {
+		return fmt.Errorf("unknown sort order %q, use %s", *sortBy, strings.Join(sortOrders, ", "))
+	}
+
+	sel, err := observery.ParseSelector(*selector)
+	if err != nil {
+		return err
+	}
+
+	client, err := newLister()
+	if err != nil {
+		return err
+	}
+
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	v := &topView{client: client, selector: sel, sortBy: *sortBy}
+	v.refresh(ctx)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		v.render(t)
+
+		select {
+		case key, ok := <-t.keys:
+			if !ok || v.handle(ctx, key) {
+				return nil
+			}
+		case <-ticker.C:
+			v.refresh(ctx)
+		case <-signals:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// refresh reloads checks and outages, and the shown check if there is one.
+func (v *topView) refresh(ctx context.Context) {
+	checks, err := v.client.ListChecks(ctx)
+	if err == nil && !checks.Success {
+		err = errors.New(checks.Reason)
+	}
+	if err != nil {
+		v.err = err
+		return
+	}
+
+	outages, err := v.client.ListOutages(ctx)
+	if err == nil && !outages.Success {
+		err = errors.New(outages.Reason)
+	}
+	if err != nil {
+		v.err = err
+		return
+	}
+
+	v.err = nil
+	if checks.Reason != "" {
+		v.message = "Partial results: " + checks.Reason
+	} else if outages.Reason != "" {
+		v.message = "Partial results: " + outages.Reason
+	}
+	v.fetched = time.Now()
+	v.all = checks.Checks
+	v.outages = outages.Outages
+	v.apply()
+
+	if v.detail != nil {
+		v.open(ctx, v.detail.Check.ID)
+	}
+}
+
+// apply filters and sorts the checks and keeps the cursor in range.
+func (v *topView) apply() {
+	v.checks = v.selector.Filter(v.all)
+
+	rank := map[string]int{"down": 0, "waiting": 1, "up": 2}
+	sort.SliceStable(v.checks, func(i, j int) bool {
+		a, b := v.checks[i], v.checks[j]
+		switch v.sortBy {
+		case "since":
+			return a.Since.After(b.Since)
+		case "name":
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+		if rank[a.State] != rank[b.State] {
+			return rank[a.State] < rank[b.State]
+		}
+		return a.Since.After(b.Since)
+	})
+
+	if v.cursor >= len(v.checks) {
+		v.cursor = len(v.checks) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+}
+
+// open shows the details of a check.
+func (v *topView) open(ctx context.Context, id string) {
+	resp, err := v.client.GetCheck(ctx, id)
+	if err == nil && !resp.Success {
+		err = errors.New(resp.Reason)
+	}
+	if err != nil {
+		v.message = "Unable to load check: " + err.Error()
+		return
+	}
+	v.detail = resp
+}
+
+// handle reacts to a key press and reports whether to quit.
+func (v *topView) handle(ctx context.Context, key string) bool {
+	if v.input != nil {
+		v.edit(key)
+		return false
+	}
+
+	if v.confirm != "" {
+		if key == "y" || key == "Y" {
+			if err := v.action(ctx); err != nil {
+				v.message = err.Error()
+			}
+			v.refresh(ctx)
+		} else {
+			v.message = "Cancelled"
+		}
+		v.confirm, v.action = "", nil
+		return false
+	}
+
+	v.message = ""
+	switch key {
+	case "q":
+		return true
+	case "r":
+		v.refresh(ctx)
+	case "a":
+		v.toggleActive()
+	case "m":
+		v.message = maintenanceMessage
+	}
+
+	if v.detail != nil {
+		if key == keyEsc || key == keyBack || key == "h" {
+			v.detail = nil
+		}
+		return false
+	}
+
+	switch key {
+	case keyUp, "k":
+		if v.cursor > 0 {
+			v.cursor--
+		}
+	case keyDown, "j":
+		if v.cursor < len(v.checks)-1 {
+			v.cursor++
+		}
+	case keyEnter, "l":
+		if c, ok := v.selected(); ok {
+			v.open(ctx, c.ID)
+		}
+	case "s":
+		for i, order := range sortOrders {
+			if order == v.sortBy {
+				v.sortBy = sortOrders[(i+1)%len(sortOrders)]
+				break
+			}
+		}
+		v.apply()
+	case "/":
+		s := v.selector.String()
+		v.input = &s
+	}
+	return false
+}
+
+// edit handles a key while a selector is typed.
+func (v *topView) edit(key string) {
+	switch key {
+	case keyEnter:
+		sel, err := observery.ParseSelector(*v.input)
+		if err != nil {
+			v.message = err.Error()
+			return
+		}
+		v.selector = sel
+		v.input = nil
+		v.apply()
+	case keyEsc:
+		v.input = nil
+	case keyBack:
+		if r := []rune(*v.input); len(r) > 0 {
+			*v.input = string(r[:len(r)-1])
+		}
+	case keyUp, keyDown:
+	default:
+		*v.input += key
+	}
+}
+
+// toggleActive asks to activate or deactivate the selected check.
+func (v *topView) toggleActive() {
+	var (
+		id, name string
+		active   bool
+	)
+	if v.detail != nil {
+		id, name, active = v.detail.Check.ID, v.detail.Check.Name, v.detail.Check.Active
+	} else if c, ok := v.selected(); ok {
+		id, name, active = c.ID, c.Name, c.Active
+	} else {
+		return
+	}
+
+	verb := "Activate"
+	if active {
+		verb = "Deactivate"
+	}
+	v.confirm = fmt.Sprintf("%s %s? [y/N]", verb, name)
+	v.action = func(ctx context.Context) error {
+		activate := !active
+		resp, err := v.client.UpdateCheck(ctx, &observery.UpdateCheckRequest{ID: id, Active: &activate})
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return errors.New(resp.Result.Message)
+		}
+		v.message = verb + "d " + name
+		return nil
+	}
+}
+
+func (v *topView) selected() (observery.Check, bool) {
+	if v.cursor < 0 || v.cursor >= len(v.checks) {
+		return observery.Check{}, false
+	}
+	return v.checks[v.cursor], true
+}
+
+// render redraws the whole screen.
+func (v *topView) render(t *terminal) {
+	rows, cols := t.size()
+
+	var b strings.Builder
+	b.WriteString(clearHome)
+	line := func(format string, args ...interface{}) {
+		s := fmt.Sprintf(format, args...)
+		b.WriteString(s + reset + clearLine + "\n")
+	}
+
+	down := 0
+	for _, c := range v.checks {
+		if c.State == "down" {
+			down++
+		}
+	}
+	updated := "never"
+	if !v.fetched.IsZero() {
+		updated = v.fetched.Format("15:04:05")
+	}
+	line("%sobservery top%s  %d checks, %d down  sort: %s  selector: %s  updated %s",
+		bold, reset, len(v.checks), down, v.sortBy, orDash(v.selector.String()), updated)
+	if v.err != nil {
+		line("%sRefresh failed: %s", red, v.err)
+	} else {
+		line("")
+	}
+
+	// Two header lines and two footer lines.
+	body := rows - 4
+	if v.detail != nil {
+		v.renderDetail(line, body)
+	} else {
+		v.renderList(line, body, cols)
+	}
+
+	switch {
+	case v.input != nil:
+		b.WriteString(fmt.Sprintf("\n%sselector:%s %s", bold, reset, *v.input))
+	case v.confirm != "":
+		b.WriteString("\n" + yellow + v.confirm + reset)
+	case v.message != "":
+		b.WriteString("\n" + v.message)
+	case v.detail != nil:
+		b.WriteString("\n" + dim + "esc back  a toggle active  m maintenance  r refresh  q quit" + reset)
+	default:
+		b.WriteString("\n" + dim + "↑/↓ move  enter details  s sort  / filter  a toggle active  m maintenance  r refresh  q quit" + reset)
+	}
+	b.WriteString(clearLine)
+
+	fmt.Print(b.String())
+}
+
+func (v *topView) renderList(line func(string, ...interface{}), body, cols int) {
+	line("%s%-8s %-10s %-6s %-8s %s", bold, "STATE", "SINCE", "TYPE", "ACTIVE", "NAME")
+
+	start := 0
+	if v.cursor >= body-1 {
+		start = v.cursor - body + 2
+	}
+	for i := start; i < len(v.checks) && i-start < body-1; i++ {
+		c := v.checks[i]
+		row := fmt.Sprintf("%-8s %-10s %-6s %-8t %s", c.State, age(c.Since), c.Type, c.Active, c.Name)
+		row = truncate(row, cols)
+		if i == v.cursor {
+			line("%s%s", reverse, row)
+		} else {
+			line("%s%s", stateColor(c.State), row)
+		}
+	}
+}
+
+func (v *topView) renderDetail(line func(string, ...interface{}), body int) {
+	c := v.detail.Check
+	line("%s%s", bold, c.Name)
+	line("  ID:          %s", c.ID)
+	line("  Type:        %s", c.Type)
+	line("  State:       %s%s", stateColor(c.State), c.State)
+	line("  Since:       %s", c.Since)
+	line("  Active:      %t", c.Active)
+	line("  Interval:    %d min", c.Interval)
+	line("  Maintenance: window %t, mode %t", c.InMaintenance, c.MaintenanceModeActive)
+	if c.URL != nil {
+		line("  URL:         %s", *c.URL)
+	}
+	if c.OutageID != nil {
+		line("  Outage:      %s", *c.OutageID)
+	}
+	line("")
+	line("%sRecent outages", bold)
+
+	shown := 0
+	for _, o := range v.outages {
+		if o.CheckID != c.ID || shown >= body-12 {
+			continue
+		}
+		shown++
+		duration := o.Duration
+		if o.Ongoing {
+			duration = time.Since(o.Start)
+		}
+		status := ""
+		if o.Ongoing {
+			status = red + " ongoing"
+		}
+		line("  %s  %-10s%s", o.Start.Local().Format("2006-01-02 15:04"), duration.Round(time.Second), status)
+	}
+	if shown == 0 {
+		line("  none")
+	}
+}
+
+func stateColor(state string) string {
+	switch state {
+	case "down":
+		return red
+	case "up":
+		return green
+	}
+	return yellow
+}
+
+// age formats how long ago t was, like "3h12m".
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// fakeLister serves a fixed set of checks.
+type fakeLister struct {
+	checks  []observery.Check
+	updates []observery.UpdateCheckRequest
+}
+
+func (f *fakeLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	return &observery.ListChecksResponse{Success: true, Checks: f.checks}, nil
+}
+
+func (f *fakeLister) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return &observery.ListOutagesResponse{Success: true}, nil
+}
+
+func (f *fakeLister) GetCheck(ctx context.Context, id string) (*observery.GetCheckResponse, error) {
+	resp := &observery.GetCheckResponse{Success: true}
+	for _, c := range f.checks {
+		if c.ID == id {
+			resp.Check.ID, resp.Check.Name, resp.Check.Active = c.ID, c.Name, c.Active
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeLister) GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error) {
+	return &observery.GetOutageResponse{Success: true}, nil
+}
+
+func (f *fakeLister) UpdateCheck(ctx context.Context, req *observery.UpdateCheckRequest) (*observery.UpdateCheckResponse, error) {
+	f.updates = append(f.updates, *req)
+	return &observery.UpdateCheckResponse{Success: true}, nil
+}
+
+func newTopView() (*topView, *fakeLister) {
+	now := time.Now()
+	client := &fakeLister{checks: []observery.Check{
+		{ID: "1", Name: "web", Type: "http", State: "up", Active: true, Since: now.Add(-3 * time.Hour)},
+		{ID: "2", Name: "Api", Type: "http", State: "down", Active: true, Since: now.Add(-2 * time.Hour)},
+		{ID: "3", Name: "db", Type: "ping", State: "waiting", Active: false, Since: now.Add(-time.Hour)},
+	}}
+	v := &topView{client: client, sortBy: "state"}
+	v.refresh(context.Background())
+	return v, client
+}
+
+func names(checks []observery.Check) string {
+	var s string
+	for _, c := range checks {
+		s += c.Name + " "
+	}
+	return s
+}
+
+func TestTopApply(t *testing.T) {
+	v, _ := newTopView()
+
+	for _, test := range []struct {
+		sortBy, want string
+	}{
+		{"state", "Api db web "},
+		{"since", "db Api web "},
+		{"name", "Api db web "},
+	} {
+		v.sortBy = test.sortBy
+		v.apply()
+		if got := names(v.checks); got != test.want {
+			t.Fatalf("Expected %q sorted by %s but got %q\n", test.want, test.sortBy, got)
+		}
+	}
+
+	v.cursor = 2
+	v.selector, _ = observery.ParseSelector("type=ping")
+	v.apply()
+	if len(v.checks) != 1 || v.cursor != 0 {
+		t.Fatalf("Expected one check with the cursor on it but got %q at %d\n", names(v.checks), v.cursor)
+	}
+
+	v.selector, _ = observery.ParseSelector("type=cert")
+	v.apply()
+	if len(v.checks) != 0 || v.cursor != 0 {
+		t.Fatalf("Expected no checks with the cursor at 0 but got %q at %d\n", names(v.checks), v.cursor)
+	}
+}
+
+func TestTopHandle(t *testing.T) {
+	ctx := context.Background()
+	v, client := newTopView()
+
+	v.handle(ctx, keyUp)
+	v.handle(ctx, keyDown)
+	v.handle(ctx, "j")
+	v.handle(ctx, keyDown)
+	if v.cursor != 2 {
+		t.Fatalf("Expected the cursor to stop at the last check but got %d\n", v.cursor)
+	}
+
+	v.handle(ctx, "s")
+	if v.sortBy != "since" {
+		t.Fatalf("Expected s to cycle the sort order but got %s\n", v.sortBy)
+	}
+
+	v.handle(ctx, keyEnter)
+	if v.detail == nil || v.detail.Check.Name != "web" {
+		t.Fatalf("Expected the selected check to be opened but got %+v\n", v.detail)
+	}
+
+	v.handle(ctx, "a")
+	if v.confirm != "Deactivate web? [y/N]" {
+		t.Fatalf("Expected to be asked to deactivate web but got %q\n", v.confirm)
+	}
+	v.handle(ctx, "y")
+	if len(client.updates) != 1 || client.updates[0].ID != "1" || *client.updates[0].Active {
+		t.Fatalf("Expected web to be deactivated but got %+v\n", client.updates)
+	}
+
+	v.handle(ctx, "a")
+	v.handle(ctx, "n")
+	if len(client.updates) != 1 || v.message != "Cancelled" {
+		t.Fatalf("Expected the update to be cancelled but got %+v and %q\n", client.updates, v.message)
+	}
+
+	v.handle(ctx, "m")
+	if v.message != maintenanceMessage {
+		t.Fatalf("Expected m to explain that maintenance can't be toggled but got %q\n", v.message)
+	}
+
+	v.handle(ctx, keyEsc)
+	if v.detail != nil {
+		t.Fatal("Expected esc to close the details")
+	}
+
+	if !v.handle(ctx, "q") {
+		t.Fatal("Expected q to quit")
+	}
+}
+
+func TestTopEdit(t *testing.T) {
+	ctx := context.Background()
+	v, _ := newTopView()
+
+	v.handle(ctx, "/")
+	for _, key := range []string{"t", "y", "p", "e", "=", "p", "i", "n", "g"} {
+		v.handle(ctx, key)
+	}
+	if v.handle(ctx, "q") || *v.input != "type=pingq" {
+		t.Fatalf("Expected q to be typed into the selector but got %q\n", *v.input)
+	}
+	v.handle(ctx, keyBack)
+	v.handle(ctx, keyEnter)
+	if v.input != nil || v.selector.String() != "type=ping" || len(v.checks) != 1 {
+		t.Fatalf("Expected the selector to be applied but got %q and %q\n", v.selector, names(v.checks))
+	}
+
+	v.handle(ctx, "/")
+	*v.input = "nosuchfield=1"
+	v.handle(ctx, keyEnter)
+	if v.input == nil || v.message == "" || v.selector.String() != "type=ping" {
+		t.Fatalf("Expected an invalid selector to be rejected but got %q\n", v.message)
+	}
+
+	*v.input = "name=café"
+	v.handle(ctx, keyBack)
+	if *v.input != "name=caf" {
+		t.Fatalf("Expected backspace to remove the last character but got %q\n", *v.input)
+	}
+
+	v.handle(ctx, keyEsc)
+	if v.input != nil || v.selector.String() != "type=ping" {
+		t.Fatal("Expected esc to leave the selector unchanged")
+	}
+}
+
+func TestTopCommandSort(t *testing.T) {
+	if err := topCommand(context.Background(), []string{"-sort", "uptime"}); err == nil || !strings.Contains(err.Error(), "unknown sort order") {
+		t.Fatalf("Expected an unknown sort order to be rejected but got %v\n", err)
+	}
+	for _, order := range sortOrders {
+		if !validSort(order) {
+			t.Fatalf("Expected %s to be a valid sort order\n", order)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"up", 5, "up"},
+		{"down", 2, "do"},
+		{"häst", 2, "hä"},
+		{"日本語", 2, "日本"},
+	} {
+		if got := truncate(test.s, test.n); got != test.want {
+			t.Fatalf("Expected %q truncated to %d to be %q but got %q\n", test.s, test.n, test.want, got)
+		}
+	}
+}
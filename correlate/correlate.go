@@ -0,0 +1,359 @@
+// Package correlate groups checks that go down at about the same time and
+// share a host, domain or label into a single incident. Each incident
+// lists its members and is resolved once all of them are back up.
+package correlate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// EventType tells what happened to an incident.
+type EventType string
+
+// The events sent by a Correlator.
+const (
+	Opened   EventType = "opened"
+	Updated  EventType = "updated"
+	Resolved EventType = "resolved"
+)
+
+// Incident is a group of correlated check failures.
+type Incident struct {
+	// ID of the incident, unique within the Correlator.
+	ID string `json:"id"`
+
+	// Keys are the shared hosts, domains or labels, like "host:db1" or
+	// "label:payments".
+	Keys []string `json:"keys"`
+
+	// Members are the checks that are part of the incident.
+	Members []Member `json:"members"`
+
+	// Start is when the first member went down.
+	Start time.Time `json:"start"`
+
+	// Resolved is when the last member came back up. Zero while open.
+	Resolved time.Time `json:"resolved,omitempty"`
+}
+
+// Open reports whether any member is still down.
+func (i *Incident) Open() bool {
+	return i.Resolved.IsZero()
+}
+
+// Member is a check that is part of an incident.
+type Member struct {
+	// CheckID of the check.
+	CheckID string `json:"checkId"`
+
+	// CheckName of the check.
+	CheckName string `json:"checkName"`
+
+	// State is the last known state of the check.
+	State string `json:"state"`
+
+	// Since is when the check entered State.
+	Since time.Time `json:"since"`
+}
+
+// Event is sent whenever an incident is opened, changes or is resolved.
+type Event struct {
+	// Type of the event.
+	Type EventType
+
+	// Incident as of the event.
+	Incident Incident
+}
+
+// Webhook returns the event in the shape of an observery webhook so it can
+// be sent with a notify.Notifier. CheckID holds the incident id, CheckName
+// lists the members and State is "incident opened", "incident updated" or
+// "incident resolved".
+func (e Event) Webhook() *observery.Webhook {
+	names := make([]string, len(e.Incident.Members))
+	down := 0
+	for i, m := range e.Incident.Members {
+		names[i] = m.CheckName
+		if m.State == "down" {
+			down++
+		}
+	}
+
+	return &observery.Webhook{
+		CheckID:   e.Incident.ID,
+		CheckName: strings.Join(names, ", "),
+		State:     "incident " + string(e.Type),
+		Details:   fmt.Sprintf("%d of %d checks down, sharing %s", down, len(names), strings.Join(e.Incident.Keys, ", ")),
+	}
+}
+
+// Correlator groups webhooks into incidents.
+type Correlator struct {
+	// Window is how long after an incident started new failures sharing a
+	// key join it. Defaults to two minutes.
+	Window time.Duration
+
+	// Labels maps check ids or names to labels used for grouping.
+	Labels map[string][]string
+
+	// Lookup finds the host or url of a check so checks on the same host
+	// or domain are grouped. Optional.
+	Lookup notify.LookupFunc
+
+	// OnEvent is called for every incident event. Optional.
+	OnEvent func(Event)
+
+	mu        sync.Mutex
+	incidents []*Incident
+	lastID    int
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NotifyEvents returns an OnEvent function that sends every event to n.
+// Errors are passed to onError, which may be nil.
+func NotifyEvents(n notify.Notifier, onError func(error)) func(Event) {
+	return func(e Event) {
+		if err := n.Notify(context.Background(), e.Webhook()); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Correlator.
+func (c *Correlator) Handler() http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			c.Observe(context.Background(), hook)
+		}
+	})
+}
+
+// ObserveStateChange feeds an event from Client.WatchChecks into the
+// Correlator.
+func (c *Correlator) ObserveStateChange(ctx context.Context, e observery.StateChangeEvent) {
+	c.Observe(ctx, e.Webhook())
+}
+
+// Observe adds a down check to a matching open incident or opens a new
+// one, and marks up checks as recovered.
+func (c *Correlator) Observe(ctx context.Context, hook *observery.Webhook) {
+	var keys []string
+	if hook.State == "down" {
+		keys = c.keys(ctx, hook)
+	}
+
+	c.mu.Lock()
+	var events []Event
+	switch hook.State {
+	case "down":
+		events = c.down(hook, keys)
+	case "up":
+		events = c.up(hook)
+	}
+	c.mu.Unlock()
+
+	if c.OnEvent != nil {
+		for _, e := range events {
+			c.OnEvent(e)
+		}
+	}
+}
+
+func (c *Correlator) down(hook *observery.Webhook, keys []string) []Event {
+	now := c.clock()
+	window := c.Window
+	if window == 0 {
+		window = 2 * time.Minute
+	}
+
+	// Already part of an open incident.
+	for _, i := range c.incidents {
+		if !i.Open() {
+			continue
+		}
+		for j := range i.Members {
+			if m := &i.Members[j]; m.CheckID == hook.CheckID {
+				if m.State == "down" {
+					return nil
+				}
+				m.State, m.Since = "down", now
+				return []Event{{Type: Updated, Incident: copyIncident(i)}}
+			}
+		}
+	}
+
+	member := Member{CheckID: hook.CheckID, CheckName: hook.CheckName, State: "down", Since: now}
+	for _, i := range c.incidents {
+		if !i.Open() || now.Sub(i.Start) > window {
+			continue
+		}
+		if shared := intersect(i.Keys, keys); len(shared) > 0 {
+			i.Keys = shared
+			i.Members = append(i.Members, member)
+			return []Event{{Type: Updated, Incident: copyIncident(i)}}
+		}
+	}
+
+	c.lastID++
+	i := &Incident{
+		ID:      strconv.Itoa(c.lastID),
+		Keys:    keys,
+		Members: []Member{member},
+		Start:   now,
+	}
+	c.incidents = append(c.incidents, i)
+	return []Event{{Type: Opened, Incident: copyIncident(i)}}
+}
+
+func (c *Correlator) up(hook *observery.Webhook) []Event {
+	now := c.clock()
+	for _, i := range c.incidents {
+		if !i.Open() {
+			continue
+		}
+		for j := range i.Members {
+			m := &i.Members[j]
+			if m.CheckID != hook.CheckID || m.State == "up" {
+				continue
+			}
+			m.State, m.Since = "up", now
+
+			for _, other := range i.Members {
+				if other.State == "down" {
+					return []Event{{Type: Updated, Incident: copyIncident(i)}}
+				}
+			}
+			i.Resolved = now
+			return []Event{{Type: Resolved, Incident: copyIncident(i)}}
+		}
+	}
+	return nil
+}
+
+// keys returns the grouping keys of the check.
+func (c *Correlator) keys(ctx context.Context, hook *observery.Webhook) []string {
+	var keys []string
+	for _, k := range []string{hook.CheckID, hook.CheckName} {
+		for _, label := range c.Labels[k] {
+			keys = append(keys, "label:"+label)
+		}
+	}
+
+	if c.Lookup != nil {
+		if check, err := c.Lookup(ctx, hook.CheckID); err == nil && check != nil {
+			host := check.Host
+			if u, err := url.Parse(check.URL); err == nil && check.URL != "" {
+				host = u.Hostname()
+			}
+			if host != "" {
+				keys = append(keys, "host:"+host)
+				if d := domain(host); d != "" && d != host {
+					keys = append(keys, "domain:"+d)
+				}
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Incidents returns a copy of all incidents, newest first. If open is true
+// only open incidents are returned.
+func (c *Correlator) Incidents(open bool) []Incident {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var incidents []Incident
+	for j := len(c.incidents) - 1; j >= 0; j-- {
+		if i := c.incidents[j]; !open || i.Open() {
+			incidents = append(incidents, copyIncident(i))
+		}
+	}
+	return incidents
+}
+
+// Prune forgets resolved incidents that were resolved before t.
+func (c *Correlator) Prune(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.incidents[:0]
+	for _, i := range c.incidents {
+		if i.Open() || i.Resolved.After(t) {
+			kept = append(kept, i)
+		}
+	}
+	c.incidents = kept
+}
+
+// ServeHTTP serves the incidents as JSON. Add ?open=true to only list open
+// incidents.
+func (c *Correlator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	open, _ := strconv.ParseBool(r.URL.Query().Get("open"))
+	incidents := c.Incidents(open)
+	if incidents == nil {
+		incidents = []Incident{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidents)
+}
+
+func (c *Correlator) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func copyIncident(i *Incident) Incident {
+	cp := *i
+	cp.Keys = append([]string(nil), i.Keys...)
+	cp.Members = append([]Member(nil), i.Members...)
+	return cp
+}
+
+// domain returns the last two labels of host, or "" for IP addresses.
+// This is a simple approximation that doesn't know about public suffixes
+// like co.uk.
+func domain(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func intersect(a, b []string) []string {
+	set := map[string]bool{}
+	for _, k := range b {
+		set[k] = true
+	}
+	var shared []string
+	for _, k := range a {
+		if set[k] {
+			shared = append(shared, k)
+		}
+	}
+	return shared
+}
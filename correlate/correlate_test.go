@@ -0,0 +1,88 @@
+package correlate
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+func TestCorrelator(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	hosts := map[string]string{
+		"1": "https://api.example.com/health",
+		"2": "https://www.example.com/",
+		"3": "https://other.org/",
+	}
+
+	var events []Event
+	c := &Correlator{
+		Window: time.Minute,
+		Lookup: func(ctx context.Context, id string) (*observery.Check, error) {
+			return &observery.Check{ID: id, URL: hosts[id]}, nil
+		},
+		OnEvent: func(e Event) { events = append(events, e) },
+		now:     func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	c.Observe(ctx, &observery.Webhook{CheckID: "1", CheckName: "api", State: "down"})
+	now = now.Add(30 * time.Second)
+	c.Observe(ctx, &observery.Webhook{CheckID: "2", CheckName: "www", State: "down"})
+	c.Observe(ctx, &observery.Webhook{CheckID: "3", CheckName: "other", State: "down"})
+
+	if len(events) != 3 || events[0].Type != Opened || events[1].Type != Updated || events[2].Type != Opened {
+		t.Fatalf("Expected opened, updated, opened but got %+v\n", events)
+	}
+	if keys := events[1].Incident.Keys; len(keys) != 1 || keys[0] != "domain:example.com" {
+		t.Fatalf("Expected the incident to share domain:example.com but got %v\n", keys)
+	}
+
+	c.Observe(ctx, &observery.Webhook{CheckID: "1", State: "up"})
+	if e := events[len(events)-1]; e.Type != Updated {
+		t.Fatalf("Expected an update while www is still down but got %s\n", e.Type)
+	}
+	c.Observe(ctx, &observery.Webhook{CheckID: "2", State: "up"})
+	if e := events[len(events)-1]; e.Type != Resolved || !e.Incident.Resolved.Equal(now) {
+		t.Fatalf("Expected the incident to be resolved but got %+v\n", e)
+	}
+
+	if open := c.Incidents(true); len(open) != 1 || open[0].Members[0].CheckID != "3" {
+		t.Fatalf("Expected only the other.org incident to be open but got %+v\n", open)
+	}
+
+	// Outside the window failures open a new incident.
+	now = now.Add(2 * time.Minute)
+	c.Observe(ctx, &observery.Webhook{CheckID: "1", State: "down"})
+	if e := events[len(events)-1]; e.Type != Opened || e.Incident.ID != "3" {
+		t.Fatalf("Expected a new incident but got %+v\n", e)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/?open=true", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"id":"3"`) || strings.Contains(body, `"id":"1"`) {
+		t.Fatalf("Unexpected incidents: %s\n", body)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var events []Event
+	c := &Correlator{
+		Labels:  map[string][]string{"a": {"payments"}, "b": {"payments", "eu"}},
+		OnEvent: func(e Event) { events = append(events, e) },
+	}
+
+	c.Observe(context.Background(), &observery.Webhook{CheckID: "1", CheckName: "a", State: "down"})
+	c.Observe(context.Background(), &observery.Webhook{CheckID: "2", CheckName: "b", State: "down"})
+	if len(events) != 2 || events[1].Type != Updated || len(events[1].Incident.Members) != 2 {
+		t.Fatalf("Expected both checks in one incident but got %+v\n", events)
+	}
+
+	hook := events[1].Webhook()
+	if hook.State != "incident updated" || hook.CheckName != "a, b" {
+		t.Fatalf("Unexpected webhook: %+v\n", hook)
+	}
+}
@@ -0,0 +1,311 @@
+package observery
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoCredentials is returned by a CredentialsProvider that has nothing to
+// offer, so a ChainProvider moves on to the next provider.
+var ErrNoCredentials = errors.New("observery: no credentials found")
+
+// Credentials hold the username and password used to authenticate with the
+// API.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsProvider supplies credentials. A Client asks its provider
+// before every request, so providers that read files pick up rotated
+// credentials without a restart.
+type CredentialsProvider interface {
+	// Credentials returns the current credentials, or ErrNoCredentials
+	// if the provider has none.
+	Credentials() (Credentials, error)
+}
+
+// StaticProvider always returns the same credentials.
+type StaticProvider Credentials
+
+// Credentials returns the static credentials.
+func (p StaticProvider) Credentials() (Credentials, error) {
+	if p.Username == "" || p.Password == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	return Credentials(p), nil
+}
+
+// EnvProvider reads the credentials from environment variables.
+type EnvProvider struct {
+	// UsernameVar and PasswordVar name the variables. They default to
+	// OBSERVERY_USERNAME and OBSERVERY_PASSWORD.
+	UsernameVar string
+	PasswordVar string
+}
+
+// Credentials returns the credentials from the environment.
+func (p EnvProvider) Credentials() (Credentials, error) {
+	userVar, passVar := p.UsernameVar, p.PasswordVar
+	if userVar == "" {
+		userVar = "OBSERVERY_USERNAME"
+	}
+	if passVar == "" {
+		passVar = "OBSERVERY_PASSWORD"
+	}
+	return StaticProvider{Username: os.Getenv(userVar), Password: os.Getenv(passVar)}.Credentials()
+}
+
+// FileProvider reads the username and password from separate files, like
+// the ones mounted by Docker, Kubernetes or Vault agents. Surrounding
+// whitespace is trimmed. The files are read on every call.
+type FileProvider struct {
+	// UsernameFile and PasswordFile are the paths of the files. They
+	// default to the paths in the OBSERVERY_USERNAME_FILE and
+	// OBSERVERY_PASSWORD_FILE environment variables.
+	UsernameFile string
+	PasswordFile string
+}
+
+// Credentials returns the credentials from the files.
+func (p FileProvider) Credentials() (Credentials, error) {
+	userFile, passFile := p.UsernameFile, p.PasswordFile
+	if userFile == "" {
+		userFile = os.Getenv("OBSERVERY_USERNAME_FILE")
+	}
+	if passFile == "" {
+		passFile = os.Getenv("OBSERVERY_PASSWORD_FILE")
+	}
+	if userFile == "" || passFile == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	username, err := ioutil.ReadFile(userFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	password, err := ioutil.ReadFile(passFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return StaticProvider{
+		Username: strings.TrimSpace(string(username)),
+		Password: strings.TrimSpace(string(password)),
+	}.Credentials()
+}
+
+// ProfileProvider reads a named profile from an INI style config file:
+//
+//	[default]
+//	username = me@example.com
+//	password = secret
+//
+//	[staging]
+//	username = staging@example.com
+//	password = other
+type ProfileProvider struct {
+	// Path of the config file. Defaults to ~/.config/observery/config,
+	// honoring XDG_CONFIG_HOME.
+	Path string
+
+	// Profile to use. Defaults to the OBSERVERY_PROFILE environment
+	// variable or "default".
+	Profile string
+}
+
+// Credentials returns the credentials of the profile. A missing file or
+// profile returns ErrNoCredentials.
+func (p ProfileProvider) Credentials() (Credentials, error) {
+	path := p.Path
+	if path == "" {
+		dir, err := configDir()
+		if err != nil {
+			return Credentials{}, ErrNoCredentials
+		}
+		path = filepath.Join(dir, "observery", "config")
+	}
+
+	profile := p.Profile
+	if profile == "" {
+		profile = os.Getenv("OBSERVERY_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Credentials{}, ErrNoCredentials
+	}
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer f.Close()
+
+	var (
+		creds   Credentials
+		section string
+		scanner = bufio.NewScanner(f)
+	)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return Credentials{}, fmt.Errorf("observery: %s:%d: expected key = value", path, n)
+		}
+		if section != profile {
+			continue
+		}
+		switch key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]); key {
+		case "username":
+			creds.Username = value
+		case "password":
+			creds.Password = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credentials{}, err
+	}
+	return StaticProvider(creds).Credentials()
+}
+
+// NetrcProvider reads the credentials from a netrc file.
+type NetrcProvider struct {
+	// Path of the netrc file. Defaults to the NETRC environment variable
+	// or ~/.netrc.
+	Path string
+
+	// Machine to look up. Defaults to api.observery.com.
+	Machine string
+}
+
+// Credentials returns the login and password of the machine, falling back
+// to the default entry.
+func (p NetrcProvider) Credentials() (Credentials, error) {
+	path := p.Path
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Credentials{}, ErrNoCredentials
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	machine := p.Machine
+	if machine == "" {
+		machine = "api.observery.com"
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Credentials{}, ErrNoCredentials
+	}
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var (
+		fields   = strings.Fields(string(data))
+		found    Credentials
+		fallback Credentials
+		current  *Credentials
+	)
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if next() == machine {
+				current = &found
+			}
+		case "default":
+			current = &fallback
+		case "login":
+			if v := next(); current != nil {
+				current.Username = v
+			}
+		case "password":
+			if v := next(); current != nil {
+				current.Password = v
+			}
+		case "account":
+			next()
+		case "macdef":
+			// Macros run until an empty line, which Fields can't see, so
+			// stop here rather than misreading them.
+			i = len(fields)
+		}
+	}
+
+	if creds, err := StaticProvider(found).Credentials(); err == nil {
+		return creds, nil
+	}
+	return StaticProvider(fallback).Credentials()
+}
+
+// ChainProvider asks each provider in order and returns the first
+// credentials found. Providers returning ErrNoCredentials are skipped, any
+// other error stops the chain.
+type ChainProvider []CredentialsProvider
+
+// Credentials returns the credentials of the first provider that has some.
+func (c ChainProvider) Credentials() (Credentials, error) {
+	for _, p := range c {
+		creds, err := p.Credentials()
+		if err == ErrNoCredentials {
+			continue
+		}
+		return creds, err
+	}
+	return Credentials{}, ErrNoCredentials
+}
+
+// DefaultChain returns the providers used by NewDefaultClient, in order of
+// precedence: environment variables, files named by
+// OBSERVERY_USERNAME_FILE and OBSERVERY_PASSWORD_FILE, the config file
+// profile and netrc.
+func DefaultChain() ChainProvider {
+	return ChainProvider{
+		EnvProvider{},
+		FileProvider{},
+		ProfileProvider{},
+		NetrcProvider{},
+	}
+}
+
+// configDir returns XDG_CONFIG_HOME or ~/.config. os.UserConfigDir isn't
+// used because it points elsewhere on macOS and Windows, while the
+// documented location is ~/.config/observery/config everywhere.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
@@ -0,0 +1,70 @@
+package observery
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialsProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "observery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	config := write("config", "# profiles\n[default]\nusername = me\npassword = one\n\n[staging]\nusername = stage\npassword = two\n")
+	netrc := write("netrc", "machine example.com login other password x\nmachine api.observery.com\n  login net\n  password three\ndefault login any password four\n")
+	user, pass := write("user", "file\n"), write("pass", " five \n")
+
+	for _, test := range []struct {
+		name     string
+		provider CredentialsProvider
+		want     Credentials
+	}{
+		{"profile", ProfileProvider{Path: config}, Credentials{"me", "one"}},
+		{"named profile", ProfileProvider{Path: config, Profile: "staging"}, Credentials{"stage", "two"}},
+		{"netrc", NetrcProvider{Path: netrc}, Credentials{"net", "three"}},
+		{"netrc default", NetrcProvider{Path: netrc, Machine: "nowhere"}, Credentials{"any", "four"}},
+		{"files", FileProvider{UsernameFile: user, PasswordFile: pass}, Credentials{"file", "five"}},
+	} {
+		got, err := test.provider.Credentials()
+		if err != nil || got != test.want {
+			t.Errorf("%s: expected %+v but got %+v, %v\n", test.name, test.want, got, err)
+		}
+	}
+
+	if _, err := (ProfileProvider{Path: config, Profile: "missing"}).Credentials(); err != ErrNoCredentials {
+		t.Errorf("Expected ErrNoCredentials for a missing profile but got %v\n", err)
+	}
+
+	chain := ChainProvider{
+		EnvProvider{UsernameVar: "OBSERVERY_TEST_UNSET", PasswordVar: "OBSERVERY_TEST_UNSET"},
+		ProfileProvider{Path: filepath.Join(dir, "missing")},
+		FileProvider{UsernameFile: user, PasswordFile: pass},
+		ProfileProvider{Path: config},
+	}
+	if got, err := chain.Credentials(); err != nil || got.Username != "file" {
+		t.Fatalf("Expected the file credentials to take precedence but got %+v, %v\n", got, err)
+	}
+
+	// Rotated secrets are picked up on the next call.
+	write("pass", "six")
+	if got, _ := chain.Credentials(); got.Password != "six" {
+		t.Fatalf("Expected the rotated password but got %q\n", got.Password)
+	}
+
+	broken := ChainProvider{ProfileProvider{Path: write("broken", "[default]\nusername\n")}, ProfileProvider{Path: config}}
+	if _, err := broken.Credentials(); err == nil || err == ErrNoCredentials {
+		t.Fatalf("Expected a parse error to stop the chain but got %v\n", err)
+	}
+}
@@ -0,0 +1,198 @@
+package dashboard
+
+// indexHTML is the whole dashboard. It is kept in the binary so the
+// dashboard works without any files next to it, and uses relative URLs so
+// it can be mounted below a path prefix with http.StripPrefix.
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>observery</title>
+<style>
+  body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; background: #111; color: #ddd; }
+  header { display: flex; justify-content: space-between; align-items: baseline; padding: 12px 20px; background: #1b1b1b; }
+  h1 { margin: 0; font-size: 20px; }
+  h2 { font-size: 15px; text-transform: uppercase; letter-spacing: .05em; color: #888; margin: 24px 0 8px; }
+  main { display: grid; grid-template-columns: 1fr 340px; gap: 20px; padding: 0 20px 20px; }
+  #tiles { display: grid; grid-template-columns: repeat(auto-fill, minmax(180px, 1fr)); gap: 10px; }
+  .tile { border-radius: 6px; padding: 10px 12px; color: #fff; }
+  .tile .name { font-weight: 600; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+  .tile .meta { font-size: 12px; opacity: .85; margin-top: 4px; }
+  .up { background: #1e7d3a; }
+  .down { background: #b3261e; animation: pulse 2s infinite; }
+  .waiting { background: #a86b00; }
+  .inactive { background: #444; }
+  @keyframes pulse { 50% { opacity: .75; } }
+  .row { display: flex; align-items: center; margin: 4px 0; font-size: 13px; }
+  .row .label { width: 160px; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+  .bar { position: relative; flex: 1; height: 14px; background: #1e7d3a; border-radius: 3px; }
+  .bar span { position: absolute; top: 0; bottom: 0; min-width: 2px; background: #b3261e; }
+  .axis { display: flex; justify-content: space-between; margin-left: 160px; font-size: 11px; color: #777; }
+  #feed { list-style: none; padding: 0; margin: 0; font-size: 13px; }
+  #feed li { padding: 6px 0; border-bottom: 1px solid #222; }
+  #feed .time { color: #777; margin-right: 6px; }
+  .state-down { color: #ff6b5e; } .state-up { color: #5fd37c; }
+  #status { font-size: 13px; color: #888; }
+  #status.live::before { content: "\25CF "; color: #5fd37c; }
+</style>
+</head>
+<body>
+<header>
+  <h1>observery <span id="summary"></span></h1>
+  <div id="status">connecting</div>
+</header>
+<main>
+  <section>
+    <h2>Checks</h2>
+    <div id="tiles"></div>
+    <h2>Outages, last 24 hours</h2>
+    <div id="timeline"></div>
+  </section>
+  <section>
+    <h2>Live feed</h2>
+    <ul id="feed"></ul>
+  </section>
+</main>
+<script>
+var state = { checks: [], outages: [], events: [] };
+var day = 24 * 3600 * 1000;
+
+// esc escapes s for use in element content and quoted attribute values.
+function esc(s) {
+  return (s == null ? "" : String(s)).replace(/[&<>"']/g, function (c) {
+    return { "&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;" }[c];
+  });
+}
+
+// stateClass returns the css class of a state. States come from webhooks,
+// so only known ones are turned into class names.
+function stateClass(state) {
+  return ["up", "down", "waiting"].indexOf(state) >= 0 ? "state-" + state : "";
+}
+
+function time(t) {
+  var d = new Date(t);
+  return d.getFullYear() < 2 ? null : d;
+}
+
+function ago(t) {
+  var d = time(t);
+  if (!d) return "";
+  var s = Math.floor((Date.now() - d) / 1000);
+  if (s < 60) return s + "s";
+  if (s < 3600) return Math.floor(s / 60) + "m";
+  if (s < 172800) return Math.floor(s / 3600) + "h " + Math.floor(s % 3600 / 60) + "m";
+  return Math.floor(s / 86400) + "d";
+}
+
+function renderTiles() {
+  var down = 0, html = "";
+  var checks = state.checks.slice().sort(function (a, b) {
+    var rank = { down: 0, waiting: 1, up: 2 };
+    var ra = a.Active ? (rank[a.State] == null ? 1 : rank[a.State]) : 3;
+    var rb = b.Active ? (rank[b.State] == null ? 1 : rank[b.State]) : 3;
+    return ra - rb || a.Name.localeCompare(b.Name);
+  });
+  checks.forEach(function (c) {
+    if (c.Active && c.State == "down") down++;
+    var cls = c.Active ? (["up", "down"].indexOf(c.State) >= 0 ? c.State : "waiting") : "inactive";
+    html += '<div class="tile ' + cls + '" title="' + esc(c.URL || c.Host) + '">' +
+      '<div class="name">' + esc(c.Name) + "</div>" +
+      '<div class="meta">' + esc(c.Type) + " &middot; " + esc(c.Active ? c.State : "inactive") +
+      (time(c.Since) ? " for " + ago(c.Since) : "") + "</div></div>";
+  });
+  document.getElementById("tiles").innerHTML = html;
+  document.getElementById("summary").textContent = "— " + state.checks.length + " checks, " + down + " down" +
+    (state.partial ? " (partial: " + state.partial + ")" : "");
+}
+
+function renderTimeline() {
+  var now = Date.now(), from = now - day, rows = {};
+  state.outages.forEach(function (o) {
+    var start = time(o.Start), stop = o.Ongoing ? new Date(now) : time(o.Stop);
+    if (!start || !stop || stop < from) return;
+    var left = Math.max(0, (start - from) / day * 100);
+    var width = Math.max(0, (Math.min(stop, now) - Math.max(start, from)) / day * 100);
+    (rows[o.CheckName] = rows[o.CheckName] || []).push(
+      '<span style="left:' + left + "%;width:" + width + '%" title="' + esc(start.toLocaleString()) + '"></span>');
+  });
+  var names = Object.keys(rows).sort(), html = "";
+  names.forEach(function (n) {
+    html += '<div class="row"><div class="label">' + esc(n) + '</div><div class="bar">' + rows[n].join("") + "</div></div>";
+  });
+  if (names.length) {
+    html += '<div class="axis"><span>24h ago</span><span>12h ago</span><span>now</span></div>';
+  } else {
+    html = '<div class="row">No outages.</div>';
+  }
+  document.getElementById("timeline").innerHTML = html;
+}
+
+function renderFeed() {
+  var html = "";
+  state.events.slice().reverse().forEach(function (e) {
+    var t = time(e.time);
+    html += '<li><span class="time">' + (t ? t.toLocaleTimeString() : "") + "</span>" +
+      "<strong>" + esc(e.checkName) + "</strong> " +
+      '<span class="' + stateClass(e.state) + '">' + esc(e.type == "state" || e.type == "webhook" ? e.state : e.type) + "</span>" +
+      (e.details ? " <small>" + esc(e.details) + "</small>" : "") + "</li>";
+  });
+  document.getElementById("feed").innerHTML = html || "<li>Waiting for events.</li>";
+}
+
+function render() {
+  renderTiles();
+  renderTimeline();
+  renderFeed();
+}
+
+function load() {
+  fetch("api/state", { credentials: "same-origin" }).then(function (r) {
+    if (!r.ok) throw new Error(r.status + " " + r.statusText);
+    return r.json();
+  }).then(function (s) {
+    s.checks = s.checks || [];
+    s.outages = s.outages || [];
+    s.events = state.events.length > (s.events || []).length ? state.events : (s.events || []);
+    state = s;
+    render();
+  }).catch(function (err) {
+    document.getElementById("status").textContent = "refresh failed: " + err.message;
+  });
+}
+
+var source = new EventSource("events");
+source.onopen = function () {
+  var s = document.getElementById("status");
+  s.className = "live";
+  s.textContent = "live";
+};
+source.onerror = function () {
+  var s = document.getElementById("status");
+  s.className = "";
+  s.textContent = "reconnecting";
+};
+source.addEventListener("check", function (m) {
+  var e = JSON.parse(m.data);
+  state.events.push(e);
+  if (state.events.length > 200) state.events.shift();
+  state.checks.forEach(function (c) {
+    if (c.ID != e.checkId) return;
+    if (e.state) { if (c.State != e.state) c.Since = e.time; c.State = e.state; }
+    if (e.type == "activated") c.Active = true;
+    if (e.type == "deactivated") c.Active = false;
+  });
+  if (e.type == "added" || e.type == "removed" || e.state == "down" || e.state == "up") {
+    load();
+  }
+  render();
+});
+
+load();
+setInterval(load, 60000);
+setInterval(renderTiles, 15000);
+</script>
+</body>
+</html>
+`
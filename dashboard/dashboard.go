@@ -0,0 +1,365 @@
+// Package dashboard serves a self-contained, read-only web dashboard of
+// observery checks. Check tiles and the outage timeline are loaded from the
+// API, and a live feed of state changes is pushed to the browser with
+// Server-Sent Events, fed by Client.WatchChecks or incoming webhooks.
+package dashboard
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Lister is the part of observery.Client used by the Server.
+type Lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+}
+
+// Event is a single entry of the live feed.
+type Event struct {
+	// Type is "state", "added", "removed", "activated", "deactivated" or
+	// "webhook".
+	Type string `json:"type"`
+
+	// CheckID and CheckName identify the check.
+	CheckID   string `json:"checkId"`
+	CheckName string `json:"checkName"`
+
+	// State is the state of the check after the event.
+	State string `json:"state"`
+
+	// Details holds extra information, like the response time of a
+	// webhook.
+	Details string `json:"details,omitempty"`
+
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+}
+
+// State is what the dashboard page loads on start and every refresh.
+type State struct {
+	// Time is when the checks and outages were fetched.
+	Time time.Time `json:"time"`
+
+	// Checks holds all checks.
+	Checks []observery.Check `json:"checks"`
+
+	// Outages holds the most recent outages.
+	Outages []observery.Outage `json:"outages"`
+
+	// Events holds the latest events, oldest first.
+	Events []Event `json:"events"`
+
+	// Partial explains why some accounts of a MultiClient couldn't be
+	// listed. Their checks and outages are missing.
+	Partial string `json:"partial,omitempty"`
+}
+
+// Server is an http.Handler serving the dashboard. Only GET and HEAD
+// requests are accepted.
+type Server struct {
+	// Username and Password enable basic auth when Username is set.
+	Username string
+	Password string
+
+	// CacheTTL is how long API results are reused between page loads.
+	// Defaults to one minute.
+	CacheTTL time.Duration
+
+	// History is the number of events kept for newly opened pages.
+	// Defaults to 50.
+	History int
+
+	client Lister
+
+	mu      sync.Mutex
+	state   State
+	fetched time.Time
+	events  []Event
+	subs    map[chan Event]bool
+
+	// refreshing is closed when the running refresh is done. Nil if none
+	// is running. refreshErr holds the error of the last refresh.
+	refreshing chan struct{}
+	refreshErr error
+}
+
+// refreshTimeout limits how long refreshing from the API may take.
+const refreshTimeout = 30 * time.Second
+
+// New creates a Server that reads from client.
+func New(client Lister) *Server {
+	return &Server{
+		CacheTTL: time.Minute,
+		History:  50,
+		client:   client,
+		subs:     map[chan Event]bool{},
+	}
+}
+
+// ServeHTTP serves the page at /, the current State as JSON at /api/state
+// and the live feed at /events.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Username != "" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="observery"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "the dashboard is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/", "/index.html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(indexHTML))
+	case "/api/state":
+		s.serveState(w, r)
+	case "/events":
+		s.serveEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(s.Password)) == 1
+}
+
+func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
+	state, err := s.State(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// State returns the checks, outages and latest events, refreshing from the
+// API if the cache has expired. Only one refresh runs at a time: the
+// request that started it waits for it, while concurrent requests get the
+// previous state right away.
+func (s *Server) State(ctx context.Context) (State, error) {
+	s.mu.Lock()
+	var err error
+	if time.Since(s.fetched) >= s.CacheTTL {
+		done := s.refreshing
+		switch {
+		case done == nil:
+			done = make(chan struct{})
+			s.refreshing = done
+			go s.refresh(done)
+		case !s.state.Time.IsZero():
+			done = nil
+		}
+
+		if done != nil {
+			s.mu.Unlock()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return State{}, ctx.Err()
+			}
+			s.mu.Lock()
+			err = s.refreshErr
+		}
+	}
+	defer s.mu.Unlock()
+
+	if err != nil {
+		return State{}, err
+	}
+	state := s.state
+	state.Events = append([]Event(nil), s.events...)
+	return state, nil
+}
+
+// refresh reloads the checks and outages, then closes done. It isn't bound
+// to a request so an impatient client can't cancel the refresh other
+// requests wait for.
+func (s *Server) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	var state State
+	checks, err := s.client.ListChecks(ctx)
+	if err == nil && !checks.Success {
+		err = errors.New(checks.Reason)
+	}
+	if err == nil {
+		var outages *observery.ListOutagesResponse
+		outages, err = s.client.ListOutages(ctx)
+		if err == nil && !outages.Success {
+			err = errors.New(outages.Reason)
+		}
+		if err == nil {
+			state = State{Time: time.Now(), Checks: checks.Checks, Outages: outages.Outages}
+			state.Partial = checks.Reason
+			if state.Partial == "" {
+				state.Partial = outages.Reason
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(done)
+	s.refreshing = nil
+	s.refreshErr = err
+	if err == nil {
+		s.state = state
+		s.fetched = state.Time
+	}
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: check\ndata: %s\n\n", data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// changes reports whether e isn't reflected in the cached checks yet. Must
+// be called with s.mu held.
+func (s *Server) changes(e Event) bool {
+	if e.Type != "webhook" {
+		return true
+	}
+	for _, c := range s.state.Checks {
+		if c.ID == e.CheckID {
+			return c.State != e.State
+		}
+	}
+	return true
+}
+
+func (s *Server) subscribe() chan Event {
+	ch := make(chan Event, 16)
+	s.mu.Lock()
+	s.subs[ch] = true
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan Event) {
+	s.mu.Lock()
+	delete(s.subs, ch)
+	s.mu.Unlock()
+}
+
+// Publish sends an event to every open page. Pages that can't keep up miss
+// events rather than slowing down the publisher. Events that change a check
+// also expire the cached checks so reloads show them.
+func (s *Server) Publish(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+	if len(s.events) > s.History {
+		s.events = s.events[len(s.events)-s.History:]
+	}
+	if s.changes(e) {
+		s.fetched = time.Time{}
+	}
+
+	for ch := range s.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Watch publishes the events of client.WatchChecks until ctx is done.
+func (s *Server) Watch(ctx context.Context, client *observery.Client, interval time.Duration, opts *observery.WatchOptions) {
+	for event := range client.WatchChecks(ctx, interval, opts) {
+		s.Publish(watchEvent(event))
+	}
+}
+
+// WebhookHandler returns an http.HandlerFunc that publishes every webhook
+// sent by observery.com. Mount it outside of the read-only Server.
+func (s *Server) WebhookHandler() http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err != nil {
+			return
+		}
+
+		details := hook.Details
+		if hook.ResponseTime > 0 {
+			details = fmt.Sprintf("%s %s", hook.ResponseTime.Round(time.Millisecond), details)
+		}
+		s.Publish(Event{
+			Type:      "webhook",
+			CheckID:   hook.CheckID,
+			CheckName: hook.CheckName,
+			State:     hook.State,
+			Details:   details,
+			Time:      time.Now(),
+		})
+	})
+}
+
+func watchEvent(event observery.WatchEvent) Event {
+	var (
+		typ   string
+		check observery.Check
+		when  time.Time
+	)
+	switch e := event.(type) {
+	case observery.StateChangeEvent:
+		typ, check, when = "state", e.Check, e.Time
+	case observery.CheckAddedEvent:
+		typ, check, when = "added", e.Check, e.Time
+	case observery.CheckRemovedEvent:
+		typ, check, when = "removed", e.Check, e.Time
+	case observery.CheckActivatedEvent:
+		typ, check, when = "activated", e.Check, e.Time
+	case observery.CheckDeactivatedEvent:
+		typ, check, when = "deactivated", e.Check, e.Time
+	}
+	return Event{Type: typ, CheckID: check.ID, CheckName: check.Name, State: check.State, Time: when}
+}
@@ -0,0 +1,264 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type fakeLister struct {
+	calls  int
+	reason string
+}
+
+func (f *fakeLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	f.calls++
+	return &observery.ListChecksResponse{
+		Success: true,
+		Reason:  f.reason,
+		Checks:  []observery.Check{{ID: "1", Name: "api", Active: true, State: "up"}},
+	}, nil
+}
+
+func (f *fakeLister) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return &observery.ListOutagesResponse{Success: true}, nil
+}
+
+func TestServer(t *testing.T) {
+	lister := &fakeLister{}
+	s := New(lister)
+	s.Username, s.Password = "noc", "secret"
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(path string, auth bool) *http.Response {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if auth {
+			req.SetBasicAuth("noc", "secret")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := get("/", false); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without credentials but got %d\n", resp.StatusCode)
+	}
+	if resp := get("/", true); resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected the page but got %d %s\n", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	req, _ := http.NewRequest("POST", srv.URL+"/api/state", nil)
+	req.SetBasicAuth("noc", "secret")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected the dashboard to be read-only but got %v %v\n", resp.StatusCode, err)
+	}
+
+	resp := get("/events", true)
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected content type %q\n", resp.Header.Get("Content-Type"))
+	}
+
+	// Wait for the subscription before publishing.
+	for i := 0; ; i++ {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("The event stream never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hook := httptest.NewRequest("POST", "/webhook", strings.NewReader("checkId=1&checkName=api&state=down&responseTime=120"))
+	hook.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.WebhookHandler()(httptest.NewRecorder(), hook)
+
+	r := bufio.NewReader(resp.Body)
+	var data string
+	for data == "" {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(data), &e); err != nil || e.Type != "webhook" || e.State != "down" {
+		t.Fatalf("Unexpected event %s: %v\n", data, err)
+	}
+
+	state, err := s.State(context.Background())
+	if err != nil || len(state.Events) != 1 || len(state.Checks) != 1 {
+		t.Fatalf("Unexpected state %+v: %v\n", state, err)
+	}
+	if lister.calls != 1 {
+		t.Fatalf("Expected a single API call but got %d\n", lister.calls)
+	}
+}
+
+// TestFeedEscaping renders the live feed of the page with node, when
+// installed, for a webhook that tries to break out of an attribute.
+func TestPartialState(t *testing.T) {
+	s := New(&fakeLister{reason: "staging: connection refused"})
+	state, err := s.State(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Partial != "staging: connection refused" || len(state.Checks) != 1 {
+		t.Fatalf("Expected the partial reason with the checks but got %+v\n", state)
+	}
+}
+
+func TestFeedEscaping(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node isn't installed")
+	}
+
+	s := New(&fakeLister{})
+	hook := httptest.NewRequest("POST", "/webhook", strings.NewReader(url.Values{
+		"checkId":      {"1"},
+		"checkName":    {"<b>api</b>"},
+		"state":        {`down" onmouseover="alert('x')`},
+		"responseTime": {"120"},
+	}.Encode()))
+	hook.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	s.WebhookHandler()(httptest.NewRecorder(), hook)
+
+	// Webhooks are handled in the background.
+	var state State
+	for i := 0; ; i++ {
+		if state, err = s.State(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(state.Events) == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("The webhook was never published")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	events, err := json.Marshal(state.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var script strings.Builder
+	for _, name := range []string{"esc", "stateClass", "time", "renderFeed"} {
+		fn := regexp.MustCompile(`(?s)function ` + name + `\(.*?\n}\n`).FindString(indexHTML)
+		if fn == "" {
+			t.Fatalf("Function %s not found in the page\n", name)
+		}
+		script.WriteString(fn)
+	}
+	script.WriteString(`
+var feed = {};
+var document = { getElementById: function () { return feed; } };
+var state = { events: ` + string(events) + ` };
+renderFeed();
+process.stdout.write(feed.innerHTML);
+`)
+
+	out, err := exec.Command(node, "-e", script.String()).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Error running the page script: %s\n%s\n", err, out)
+	}
+	html := string(out)
+	if strings.Contains(html, `" onmouseover`) || strings.Contains(html, "<b>") || strings.Contains(html, "'x'") {
+		t.Fatalf("The webhook wasn't escaped: %s\n", html)
+	}
+	if !strings.Contains(html, "&quot; onmouseover=&quot;alert(&#39;x&#39;)") || !strings.Contains(html, `<span class="">`) {
+		t.Fatalf("Unexpected feed %s\n", html)
+	}
+}
+
+// slowLister blocks every call after the first until release is closed.
+type slowLister struct {
+	mu      sync.Mutex
+	calls   int
+	release chan struct{}
+}
+
+func (f *slowLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	first := f.calls == 1
+	f.mu.Unlock()
+
+	if !first {
+		<-f.release
+	}
+	return &observery.ListChecksResponse{Success: true, Checks: []observery.Check{{ID: "1", Name: "api", Active: true, State: "up"}}}, nil
+}
+
+func (f *slowLister) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return &observery.ListOutagesResponse{Success: true}, nil
+}
+
+func TestStateSingleRefresh(t *testing.T) {
+	lister := &slowLister{release: make(chan struct{})}
+	s := New(lister)
+	s.CacheTTL = -1
+	if _, err := s.State(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed := make(chan error)
+	go func() {
+		_, err := s.State(context.Background())
+		refreshed <- err
+	}()
+	for i := 0; ; i++ {
+		lister.mu.Lock()
+		calls := lister.calls
+		lister.mu.Unlock()
+		if calls == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("The refresh never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if state, err := s.State(context.Background()); err != nil || len(state.Checks) != 1 {
+				t.Errorf("Expected the cached state but got %+v: %v\n", state, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(lister.release)
+	if err := <-refreshed; err != nil {
+		t.Fatal(err)
+	}
+	if lister.calls != 2 {
+		t.Fatalf("Expected a single refresh but the API was called %d times\n", lister.calls)
+	}
+}
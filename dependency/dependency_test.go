@@ -0,0 +1,168 @@
+package dependency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type recorder struct {
+	hooks []observery.Webhook
+
+	// fail makes notifications for this check name fail.
+	fail string
+}
+
+func (r *recorder) Notify(ctx context.Context, hook *observery.Webhook) error {
+	if hook.CheckName == r.fail {
+		return errors.New("notifier unavailable")
+	}
+	r.hooks = append(r.hooks, *hook)
+	return nil
+}
+
+func TestParseGraph(t *testing.T) {
+	_, err := ParseGraph([]byte("dependencies:\n  a: [b]\n  b: [c]\n  c: [a]\n"))
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Fatalf("Expected a cycle error but got %v\n", err)
+	}
+
+	g, err := ParseGraph([]byte("dependencies:\n  api: [db]\n  web: [api, cdn]\n"))
+	if err != nil {
+		t.Fatalf("Error parsing graph: %s\n", err)
+	}
+
+	var buf bytes.Buffer
+	g.WriteDOT(&buf)
+	want := "digraph dependencies {\n  \"api\" -> \"db\";\n  \"web\" -> \"api\";\n  \"web\" -> \"cdn\";\n}\n"
+	if buf.String() != want {
+		t.Fatalf("Expected:\n%s\nGot:\n%s\n", want, buf.String())
+	}
+}
+
+func TestSuppressor(t *testing.T) {
+	g, _ := NewGraph(map[string][]string{"api": {"db"}, "web": {"api"}})
+	var (
+		ctx  = context.Background()
+		next = &recorder{}
+		s    = &Suppressor{Graph: g, Next: next}
+	)
+
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "2", CheckName: "api", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "3", CheckName: "web", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "3", CheckName: "web", State: "up"})
+
+	if len(next.hooks) != 1 || next.hooks[0].CheckName != "db" {
+		t.Fatalf("Expected only the root cause but got %+v\n", next.hooks)
+	}
+	if parent, ok := s.Suppressed("2"); !ok || parent != "db" {
+		t.Fatalf("Expected api to be suppressed by db but got %q\n", parent)
+	}
+
+	// web recovered on its own, so only api is blamed on db.
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "up"})
+	if len(next.hooks) != 4 || next.hooks[1].State != "root-cause" || next.hooks[1].Details != "caused api to go down" {
+		t.Fatalf("Expected the root cause of api before db up but got %+v\n", next.hooks)
+	}
+	if next.hooks[2].State != "up" || next.hooks[3].CheckName != "api" || next.hooks[3].State != "down" {
+		t.Fatalf("Expected db up followed by the released api but got %+v\n", next.hooks)
+	}
+	if _, ok := s.Suppressed("2"); ok {
+		t.Fatal("Expected api to be released")
+	}
+}
+
+func TestSuppressorHold(t *testing.T) {
+	g, _ := NewGraph(map[string][]string{"api": {"db"}, "web": {"db"}})
+	var (
+		ctx  = context.Background()
+		now  = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+		next = &recorder{}
+		s    = &Suppressor{Graph: g, Next: next, Hold: time.Minute, now: func() time.Time { return now }}
+	)
+
+	// The child's webhook arrives before its parent's.
+	s.Notify(ctx, &observery.Webhook{CheckID: "2", CheckName: "api", State: "down"})
+	if len(next.hooks) != 0 {
+		t.Fatalf("Expected the child to be held but got %+v\n", next.hooks)
+	}
+	now = now.Add(10 * time.Second)
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "down"})
+	now = now.Add(time.Minute)
+	s.Flush(ctx)
+	if len(next.hooks) != 2 || next.hooks[0].CheckName != "db" || next.hooks[1].State != "root-cause" {
+		t.Fatalf("Expected only the parent and its root cause but got %+v\n", next.hooks)
+	}
+	if parent, ok := s.Suppressed("2"); !ok || parent != "db" {
+		t.Fatalf("Expected api to be suppressed by db but got %q\n", parent)
+	}
+
+	// Without the parent going down the child is sent once Hold expires.
+	next.hooks = nil
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "up"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "3", CheckName: "web", State: "down"})
+	s.Flush(ctx)
+	if len(next.hooks) != 2 || next.hooks[1].CheckName != "api" {
+		t.Fatalf("Expected db up and the released api but got %+v\n", next.hooks)
+	}
+	now = now.Add(time.Minute)
+	s.Flush(ctx)
+	if len(next.hooks) != 3 || next.hooks[2].CheckName != "web" {
+		t.Fatalf("Expected web after its hold but got %+v\n", next.hooks)
+	}
+}
+
+func TestSuppressorSendErrors(t *testing.T) {
+	g, _ := NewGraph(map[string][]string{"api": {"db"}, "web": {"db"}})
+	var (
+		ctx  = context.Background()
+		next = &recorder{}
+		s    = &Suppressor{Graph: g, Next: next}
+	)
+
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "2", CheckName: "api", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "3", CheckName: "web", State: "down"})
+
+	next.hooks, next.fail = nil, "api"
+	if err := s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "up"}); err == nil {
+		t.Fatal("Expected the failed notification to be returned")
+	}
+	if len(next.hooks) != 3 || next.hooks[2].CheckName != "web" {
+		t.Fatalf("Expected web to be released despite api failing but got %+v\n", next.hooks)
+	}
+}
+
+func TestSuppressorRootCause(t *testing.T) {
+	g, _ := NewGraph(map[string][]string{"api": {"db"}, "web": {"api"}, "jobs": {"db"}})
+	var (
+		ctx  = context.Background()
+		next = &recorder{}
+		s    = &Suppressor{Graph: g, Next: next}
+	)
+
+	s.Notify(ctx, &observery.Webhook{CheckID: "1", CheckName: "db", State: "down", Details: "connection refused"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "2", CheckName: "api", State: "down"})
+	s.Notify(ctx, &observery.Webhook{CheckID: "3", CheckName: "web", State: "down"})
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := observery.Webhook{CheckID: "1", CheckName: "db", State: "root-cause", Details: "caused api, web to go down"}
+	if len(next.hooks) != 2 || next.hooks[1] != want {
+		t.Fatalf("Expected a single root cause for api and web but got %+v\n", next.hooks)
+	}
+
+	s.Flush(ctx)
+	s.Notify(ctx, &observery.Webhook{CheckID: "4", CheckName: "jobs", State: "down"})
+	s.Flush(ctx)
+	if len(next.hooks) != 3 || next.hooks[2].Details != "caused jobs to go down" {
+		t.Fatalf("Expected a root cause for the new child only but got %+v\n", next.hooks)
+	}
+}
@@ -0,0 +1,145 @@
+// Package dependency models checks that depend on other checks. When a
+// parent check is down, failures of the checks depending on it are
+// suppressed so only the root cause is notified, followed by a single
+// "root-cause" notification listing the children. Once the parent recovers,
+// children that are still down are released and notified. Since webhooks
+// of a child can arrive before its parent's, Suppressor.Hold delays them
+// for a short while.
+package dependency
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Graph holds the dependencies between checks. Checks are referred to by
+// id or name.
+type Graph struct {
+	parents map[string][]string
+}
+
+// Config is the YAML representation of a Graph:
+//
+//	dependencies:
+//	  api: [database]
+//	  checkout: [api, payments]
+type Config struct {
+	// Dependencies maps a check to the checks it depends on.
+	Dependencies map[string][]string `yaml:"dependencies"`
+}
+
+// LoadGraph reads a YAML dependency file.
+func LoadGraph(file string) (*Graph, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGraph(data)
+}
+
+// ParseGraph parses a YAML dependency configuration. It returns an error if
+// the dependencies contain a cycle.
+func ParseGraph(data []byte) (*Graph, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	return NewGraph(config.Dependencies)
+}
+
+// NewGraph creates a Graph from a map of checks to the checks they depend
+// on. It returns an error if the dependencies contain a cycle.
+func NewGraph(dependencies map[string][]string) (*Graph, error) {
+	g := &Graph{parents: map[string][]string{}}
+	for child, parents := range dependencies {
+		g.parents[child] = append([]string(nil), parents...)
+	}
+
+	if cycle := g.cycle(); cycle != nil {
+		return nil, fmt.Errorf("dependency: cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+	return g, nil
+}
+
+// Parents returns the checks key directly depends on.
+func (g *Graph) Parents(key string) []string {
+	return g.parents[key]
+}
+
+// cycle returns the checks forming a cycle, or nil if there is none.
+func (g *Graph) cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	var (
+		state = map[string]int{}
+		stack []string
+		found []string
+	)
+
+	var visit func(key string) bool
+	visit = func(key string) bool {
+		switch state[key] {
+		case visiting:
+			for i, k := range stack {
+				if k == key {
+					found = append(append([]string(nil), stack[i:]...), key)
+				}
+			}
+			return true
+		case done:
+			return false
+		}
+
+		state[key] = visiting
+		stack = append(stack, key)
+		for _, parent := range g.parents[key] {
+			if visit(parent) {
+				return true
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[key] = done
+		return false
+	}
+
+	for _, key := range g.keys() {
+		if visit(key) {
+			return found
+		}
+	}
+	return nil
+}
+
+// keys returns the children of the graph in order so output is stable.
+func (g *Graph) keys() []string {
+	keys := make([]string, 0, len(g.parents))
+	for k := range g.parents {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Edges point from a
+// check to the checks it depends on.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	for _, child := range g.keys() {
+		for _, parent := range g.parents[child] {
+			fmt.Fprintf(&b, "  %q -> %q;\n", child, parent)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,345 @@
+package dependency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// Suppressor is a notify.Notifier that forwards webhooks to Next unless a
+// check the webhook's check depends on is down.
+//
+// Instead of the suppressed webhooks Next receives a single "root-cause"
+// webhook per parent, a copy of the parent's down webhook whose Details
+// list the children it took down. It is sent by Flush, or before the
+// parent's up webhook, and only for children suppressed since the last
+// one.
+type Suppressor struct {
+	// Graph holds the dependencies.
+	Graph *Graph
+
+	// Next receives the root cause webhooks and released children.
+	Next notify.Notifier
+
+	// Hold delays the down webhooks of checks that have parents, so a
+	// parent whose webhook arrives shortly after its child's still
+	// suppresses the child. Held webhooks are sent by Flush, which Run
+	// calls periodically. Zero sends them right away.
+	Hold time.Duration
+
+	mu sync.Mutex
+
+	// down holds the last down webhook of every check that is down, keyed
+	// by both check id and name.
+	down map[string]*observery.Webhook
+
+	// suppressed maps the id of a suppressed check to the key of the
+	// parent that caused the suppression.
+	suppressed map[string]string
+
+	// causes holds the children suppressed since the last "root-cause"
+	// webhook of each parent, keyed by the parent's key.
+	causes map[string][]*observery.Webhook
+
+	// held holds the down webhooks of children waiting for Hold, keyed
+	// by check id.
+	held map[string]*heldHook
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+type heldHook struct {
+	hook  *observery.Webhook
+	until time.Time
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Suppressor. Errors are passed to onError, which
+// may be nil.
+func (s *Suppressor) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = s.Notify(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// ObserveStateChange feeds an event from Client.WatchChecks into the
+// Suppressor.
+func (s *Suppressor) ObserveStateChange(ctx context.Context, e observery.StateChangeEvent) error {
+	return s.Notify(ctx, e.Webhook())
+}
+
+// Suppressed returns the parent that is suppressing the check, if any.
+func (s *Suppressor) Suppressed(checkID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent, ok := s.suppressed[checkID]
+	return parent, ok
+}
+
+// Notify forwards hook to Next unless one of the check's ancestors is
+// down, so only the root cause is notified. When a parent comes back up its
+// suppressed children that are still down are forwarded after it.
+func (s *Suppressor) Notify(ctx context.Context, hook *observery.Webhook) error {
+	s.mu.Lock()
+	if s.down == nil {
+		s.down = map[string]*observery.Webhook{}
+		s.suppressed = map[string]string{}
+		s.causes = map[string][]*observery.Webhook{}
+		s.held = map[string]*heldHook{}
+	}
+
+	var sends []*observery.Webhook
+	switch hook.State {
+	case "down":
+		copied := *hook
+		s.down[hook.CheckID] = &copied
+		s.down[hook.CheckName] = &copied
+
+		if parent, ok := s.downAncestor(hook); ok {
+			s.suppress(&copied, parent)
+		} else if s.Hold > 0 && s.hasParents(hook) {
+			s.held[hook.CheckID] = &heldHook{hook: &copied, until: s.clock().Add(s.Hold)}
+		} else {
+			sends = append(sends, hook)
+		}
+		s.suppressHeld()
+
+	case "up":
+		sends = append(sends, s.rootCause(hook.CheckID)...)
+		sends = append(sends, s.rootCause(hook.CheckName)...)
+		delete(s.down, hook.CheckID)
+		delete(s.down, hook.CheckName)
+
+		parent, suppressed := s.suppressed[hook.CheckID]
+		_, held := s.held[hook.CheckID]
+		if suppressed || held {
+			// Its down was never sent, so neither is its up.
+			delete(s.suppressed, hook.CheckID)
+			delete(s.held, hook.CheckID)
+			s.unsuppress(hook.CheckID, parent)
+		} else {
+			sends = append(sends, hook)
+		}
+		sends = append(sends, s.release()...)
+
+	default:
+		_, suppressed := s.suppressed[hook.CheckID]
+		_, held := s.held[hook.CheckID]
+		if !suppressed && !held {
+			sends = append(sends, hook)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.send(ctx, sends)
+}
+
+// Flush sends the "root-cause" webhooks of parents that suppressed
+// children since their last one, and the held down webhooks whose Hold
+// expired without a parent going down. It is called periodically by Run.
+func (s *Suppressor) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	var (
+		now   = s.clock()
+		keys  []string
+		ids   []string
+		sends []*observery.Webhook
+	)
+	for key := range s.causes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sends = append(sends, s.rootCause(key)...)
+	}
+
+	for id, h := range s.held {
+		if !now.Before(h.until) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		sends = append(sends, s.held[id].hook)
+		delete(s.held, id)
+	}
+	s.mu.Unlock()
+
+	return s.send(ctx, sends)
+}
+
+// Run calls Flush every interval until ctx is done. Errors are passed to
+// onError, which may be nil.
+func (s *Suppressor) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := s.Flush(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// send forwards every hook to Next, even if some fail, and returns the
+// first error.
+func (s *Suppressor) send(ctx context.Context, hooks []*observery.Webhook) error {
+	var first error
+	for _, h := range hooks {
+		if err := s.Next.Notify(ctx, h); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// hasParents reports whether hook's check depends on another check.
+func (s *Suppressor) hasParents(hook *observery.Webhook) bool {
+	return len(s.Graph.Parents(hook.CheckID)) > 0 || len(s.Graph.Parents(hook.CheckName)) > 0
+}
+
+// suppressHeld suppresses the held children that now have an ancestor
+// down. Must be called with s.mu held.
+func (s *Suppressor) suppressHeld() {
+	for id, h := range s.held {
+		if parent, ok := s.downAncestor(h.hook); ok {
+			s.suppress(h.hook, parent)
+			delete(s.held, id)
+		}
+	}
+}
+
+// suppress marks hook's check as suppressed by parent. Must be called with
+// s.mu held.
+func (s *Suppressor) suppress(hook *observery.Webhook, parent string) {
+	s.suppressed[hook.CheckID] = parent
+	s.causes[parent] = append(s.causes[parent], hook)
+}
+
+// unsuppress removes a child that came back up from the ones waiting for
+// the root cause webhook of parent. Must be called with s.mu held.
+func (s *Suppressor) unsuppress(checkID, parent string) {
+	children := s.causes[parent][:0]
+	for _, h := range s.causes[parent] {
+		if h.CheckID != checkID {
+			children = append(children, h)
+		}
+	}
+	if len(children) == 0 {
+		delete(s.causes, parent)
+	} else {
+		s.causes[parent] = children
+	}
+}
+
+// rootCause returns the "root-cause" webhook of the parent with the given
+// key for the children suppressed since its last one, if there are any.
+// Must be called with s.mu held.
+func (s *Suppressor) rootCause(key string) []*observery.Webhook {
+	children := s.causes[key]
+	if len(children) == 0 {
+		return nil
+	}
+	delete(s.causes, key)
+
+	h := observery.Webhook{CheckName: key}
+	if parent := s.down[key]; parent != nil {
+		h = *parent
+	}
+	names := make([]string, len(children))
+	for i, c := range children {
+		names[i] = c.CheckName
+		if names[i] == "" {
+			names[i] = c.CheckID
+		}
+	}
+	h.State = "root-cause"
+	h.Details = "caused " + strings.Join(names, ", ") + " to go down"
+	return []*observery.Webhook{&h}
+}
+
+func (s *Suppressor) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// downAncestor returns the key of the topmost ancestor of hook's check
+// that is down. Must be called with s.mu held.
+func (s *Suppressor) downAncestor(hook *observery.Webhook) (string, bool) {
+	var (
+		root  string
+		found bool
+		seen  = map[string]bool{}
+	)
+
+	var walk func(key string)
+	walk = func(key string) {
+		for _, parent := range s.Graph.Parents(key) {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			if _, down := s.down[parent]; down {
+				root, found = parent, true
+			}
+			walk(parent)
+		}
+	}
+	walk(hook.CheckID)
+	walk(hook.CheckName)
+
+	return root, found
+}
+
+// release stops suppressing children whose ancestors are all up again and
+// returns the down webhooks of those that are still down. Must be called
+// with s.mu held.
+func (s *Suppressor) release() []*observery.Webhook {
+	ids := make([]string, 0, len(s.suppressed))
+	for id := range s.suppressed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var released []*observery.Webhook
+	for _, id := range ids {
+		parent, hook := s.suppressed[id], s.down[id]
+		if hook == nil {
+			continue
+		}
+		if root, ok := s.downAncestor(hook); ok {
+			s.suppressed[id] = root
+			continue
+		}
+
+		delete(s.suppressed, id)
+		h := *hook
+		note := fmt.Sprintf("still down after %s recovered", parent)
+		if h.Details != "" {
+			h.Details += " (" + note + ")"
+		} else {
+			h.Details = note
+		}
+		released = append(released, &h)
+	}
+	return released
+}
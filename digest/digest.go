@@ -0,0 +1,242 @@
+// Package digest builds periodic uptime summaries of observery checks and
+// sends them as multipart HTML and plain text email. A digest lists the
+// uptime, number of outages, longest outage and current state of every
+// check or group of checks, and what changed since the previous digest.
+package digest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/internal/atomicfile"
+	"github.com/sfreiberg/observery/slo"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config holds the optional settings of a digest.
+//
+//	subject: Weekly uptime
+//	period: 7d
+//	groups:
+//	  - name: Checkout
+//	    checks: [api, payments]
+type Config struct {
+	// Subject of the email. Defaults to "Uptime digest".
+	Subject string `yaml:"subject"`
+
+	// Period is how far back the digest looks, like "7d" or "36h".
+	// Defaults to one week.
+	Period slo.Duration `yaml:"period"`
+
+	// Groups combine several checks into one line. A group is down while
+	// any of its checks is down. Checks not in a group get their own line
+	// unless HideUngrouped is set.
+	Groups []Group `yaml:"groups"`
+
+	// HideUngrouped leaves out checks that aren't part of a group.
+	HideUngrouped bool `yaml:"hideUngrouped"`
+}
+
+// Group is a named set of checks, referred to by id or name.
+type Group struct {
+	Name   string   `yaml:"name"`
+	Checks []string `yaml:"checks"`
+}
+
+// LoadConfig reads a YAML digest configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Digest is the summary of one period.
+type Digest struct {
+	// Subject of the email.
+	Subject string `json:"subject"`
+
+	// From and To delimit the period the digest covers.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Entries holds a line per check or group, ordered by name.
+	Entries []Entry `json:"entries"`
+}
+
+// Entry summarizes a single check or group.
+type Entry struct {
+	// Name of the check or group.
+	Name string `json:"name"`
+
+	// State is "down" if any check is down, "up" if all active checks
+	// are up and "paused" if no check is active.
+	State string `json:"state"`
+
+	// Uptime within the period in percent.
+	Uptime float64 `json:"uptime"`
+
+	// Outages is the number of outages that started within the period.
+	Outages int `json:"outages"`
+
+	// Longest is the duration of the longest outage within the period.
+	Longest time.Duration `json:"longest"`
+
+	// Changes compares the entry to the previous digest. Nil if the entry
+	// is new or there is no previous digest.
+	Changes *Changes `json:"-"`
+}
+
+// Changes describes how an entry changed since the previous digest.
+type Changes struct {
+	// PreviousState is the state in the previous digest.
+	PreviousState string
+
+	// Uptime is the difference in uptime, in percentage points.
+	Uptime float64
+
+	// Outages is the difference in the number of outages.
+	Outages int
+}
+
+// StateChanged reports whether the state differs from the previous digest.
+func (c *Changes) StateChanged(state string) bool {
+	return c != nil && c.PreviousState != state
+}
+
+// Generate builds the digest of the period ending at now. When prev isn't
+// nil every entry is compared to the entry of the same name in prev.
+func Generate(checks []observery.Check, outages []observery.Outage, config *Config, prev *Digest, now time.Time) *Digest {
+	if config == nil {
+		config = &Config{}
+	}
+
+	d := &Digest{
+		Subject: config.Subject,
+		From:    now.Add(-time.Duration(config.Period)),
+		To:      now,
+	}
+	if d.Subject == "" {
+		d.Subject = "Uptime digest"
+	}
+	if config.Period == 0 {
+		d.From = now.Add(-7 * 24 * time.Hour)
+	}
+
+	grouped := map[string]bool{}
+	for _, g := range config.Groups {
+		d.Entries = append(d.Entries, d.entry(g.Name, g.Checks, checks, outages))
+		for _, c := range g.Checks {
+			grouped[c] = true
+		}
+	}
+	if !config.HideUngrouped {
+		for _, c := range checks {
+			if !grouped[c.ID] && !grouped[c.Name] {
+				d.Entries = append(d.Entries, d.entry(c.Name, []string{c.ID}, checks, outages))
+			}
+		}
+	}
+
+	sort.SliceStable(d.Entries, func(i, j int) bool {
+		return d.Entries[i].Name < d.Entries[j].Name
+	})
+
+	if prev != nil {
+		previous := map[string]Entry{}
+		for _, e := range prev.Entries {
+			previous[e.Name] = e
+		}
+		for i := range d.Entries {
+			e := &d.Entries[i]
+			if p, ok := previous[e.Name]; ok {
+				e.Changes = &Changes{
+					PreviousState: p.State,
+					Uptime:        e.Uptime - p.Uptime,
+					Outages:       e.Outages - p.Outages,
+				}
+			}
+		}
+	}
+	return d
+}
+
+func (d *Digest) entry(name string, keys []string, checks []observery.Check, outages []observery.Outage) Entry {
+	member := func(id, name string) bool {
+		for _, k := range keys {
+			if k == id || k == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	e := Entry{Name: name, State: "paused"}
+	for _, c := range checks {
+		if !member(c.ID, c.Name) || !c.Active {
+			continue
+		}
+		if c.State == "down" {
+			e.State = "down"
+		} else if e.State == "paused" {
+			e.State = "up"
+		}
+	}
+
+	for _, o := range outages {
+		if !member(o.CheckID, o.CheckName) || o.Start.Before(d.From) || o.Start.After(d.To) {
+			continue
+		}
+		e.Outages++
+
+		stop := o.Stop
+		if o.Ongoing || stop.IsZero() {
+			stop = d.To
+		}
+		if duration := stop.Sub(o.Start); duration > e.Longest {
+			e.Longest = duration
+		}
+	}
+
+	period := d.To.Sub(d.From)
+	down := slo.Downtime(slo.Objective{Checks: keys}, outages, d.From, d.To)
+	e.Uptime = 100 * (1 - float64(down)/float64(period))
+	return e
+}
+
+// Load reads a digest saved with Save. A missing file returns nil and no
+// error, so the first digest simply has no changes.
+func Load(path string) (*Digest, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Save writes the digest to path so the next one can report changes.
+func (d *Digest) Save(path string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0600)
+}
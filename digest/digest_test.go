@@ -0,0 +1,211 @@
+package digest
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+func TestGenerate(t *testing.T) {
+	now := time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC)
+	checks := []observery.Check{
+		{ID: "1", Name: "api", Active: true, State: "up"},
+		{ID: "2", Name: "payments", Active: true, State: "down"},
+		{ID: "3", Name: "blog", Active: true, State: "up"},
+		{ID: "4", Name: "legacy", Active: false, State: "down"},
+	}
+	outages := []observery.Outage{
+		{CheckID: "1", CheckName: "api", Start: now.Add(-48 * time.Hour), Stop: now.Add(-48*time.Hour + 84*time.Minute)},
+		{CheckID: "2", CheckName: "payments", Ongoing: true, Start: now.Add(-42 * time.Minute)},
+		{CheckID: "3", CheckName: "blog", Start: now.Add(-10 * 24 * time.Hour), Stop: now.Add(-9 * 24 * time.Hour)},
+	}
+	config := &Config{Groups: []Group{{Name: "Checkout", Checks: []string{"api", "payments"}}}}
+	prev := &Digest{Entries: []Entry{{Name: "Checkout", State: "up", Uptime: 100, Outages: 0}}}
+
+	d := Generate(checks, outages, config, prev, now)
+	if len(d.Entries) != 3 {
+		t.Fatalf("Expected 3 entries but got %+v\n", d.Entries)
+	}
+
+	checkout, blog, legacy := d.Entries[0], d.Entries[1], d.Entries[2]
+	if blog.Uptime != 100 || blog.Outages != 0 || blog.Changes != nil {
+		t.Fatalf("Unexpected blog entry: %+v\n", blog)
+	}
+	if checkout.State != "down" || checkout.Outages != 2 || checkout.Longest != 84*time.Minute || checkout.Uptime != 98.75 {
+		t.Fatalf("Unexpected checkout entry: %+v\n", checkout)
+	}
+	if !checkout.Changes.StateChanged(checkout.State) || checkout.Changes.Outages != 2 {
+		t.Fatalf("Unexpected changes: %+v\n", checkout.Changes)
+	}
+	if legacy.State != "paused" {
+		t.Fatalf("Expected an inactive check to be paused but got %s\n", legacy.State)
+	}
+
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "last.json")
+	if err := d.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Load(path)
+	if err != nil || len(saved.Entries) != 3 || saved.Entries[0].Uptime != 98.75 {
+		t.Fatalf("Unexpected saved digest %+v: %v\n", saved, err)
+	}
+}
+
+// smtpServer is a minimal SMTP stand-in that accepts a single message.
+func smtpServer(t *testing.T) (string, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH"):
+				reply("235 ok")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				var msg strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					msg.WriteString(line)
+				}
+				messages <- msg.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func TestSend(t *testing.T) {
+	addr, messages := smtpServer(t)
+
+	d := &Digest{
+		Subject: "Weekly uptime",
+		From:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+		Entries: []Entry{{Name: "api <v2>", State: "up", Uptime: 99.5, Outages: 1, Longest: time.Hour}},
+	}
+	m := &Mailer{Addr: addr, Username: "user", Password: "secret", From: "uptime@example.com", To: []string{"boss@example.com"}}
+	if err := m.Send(d); err != nil {
+		t.Fatalf("Error sending digest: %s\n", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-messages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Subject") != "Weekly uptime" {
+		t.Fatalf("Unexpected subject %q\n", msg.Header.Get("Subject"))
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+"\n"+string(body))
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected a text and an html part but got %d parts\n", len(parts))
+	}
+	if !strings.Contains(parts[0], "api <v2>: up") || !strings.Contains(parts[0], "longest 1h0m0s") {
+		t.Fatalf("Unexpected text part:\n%s\n", parts[0])
+	}
+	if !strings.HasPrefix(parts[1], "text/html") || !strings.Contains(parts[1], "api &lt;v2&gt;") {
+		t.Fatalf("Unexpected html part:\n%s\n", parts[1])
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Accept the connection but never greet.
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	m := &Mailer{Addr: l.Addr().String(), From: "uptime@example.com", To: []string{"boss@example.com"}, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := m.Send(&Digest{}); err == nil {
+		t.Fatal("Expected a stalled server to time out")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Expected Send to give up after the timeout but it took %s\n", time.Since(start))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "digest.yaml")
+	if err := ioutil.WriteFile(path, []byte("period: 7d\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil || time.Duration(config.Period) != 7*24*time.Hour {
+		t.Fatalf("Expected a period of 7 days but got %+v: %v\n", config, err)
+	}
+}
@@ -0,0 +1,106 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	texttemplate "text/template"
+	"time"
+)
+
+// DefaultText is the plain text template used when Templates.Text is
+// empty. Templates are executed with a *Digest.
+const DefaultText = `{{.Subject}}
+{{date .From}} to {{date .To}}
+{{range .Entries}}
+{{.Name}}: {{.State}}{{if .Changes.StateChanged .State}} (was {{.Changes.PreviousState}}){{end}}
+  Uptime:  {{percent .Uptime}}{{with .Changes}} ({{signed .Uptime}} points){{end}}
+  Outages: {{.Outages}}{{with .Changes}} ({{printf "%+d" .Outages}}){{end}}{{if .Outages}}, longest {{duration .Longest}}{{end}}
+{{end}}`
+
+// DefaultHTML is the HTML template used when Templates.HTML is empty.
+const DefaultHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222;">
+<h2>{{.Subject}}</h2>
+<p>{{date .From}} to {{date .To}}</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr style="text-align: left; border-bottom: 1px solid #ccc;"><th>Check</th><th>State</th><th>Uptime</th><th>Outages</th><th>Longest</th></tr>
+{{range .Entries}}<tr style="border-bottom: 1px solid #eee;">
+<td>{{.Name}}</td>
+<td style="color: {{if eq .State "down"}}#c0392b{{else if eq .State "up"}}#27ae60{{else}}#888{{end}};">{{.State}}{{if .Changes.StateChanged .State}} <small>(was {{.Changes.PreviousState}})</small>{{end}}</td>
+<td>{{percent .Uptime}}{{with .Changes}} <small>({{signed .Uptime}})</small>{{end}}</td>
+<td>{{.Outages}}{{with .Changes}} <small>({{printf "%+d" .Outages}})</small>{{end}}</td>
+<td>{{if .Outages}}{{duration .Longest}}{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// Templates holds the templates a digest is rendered with. Empty fields
+// use DefaultText and DefaultHTML. The functions date, duration, percent
+// and signed are available.
+type Templates struct {
+	Text string
+	HTML string
+}
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("Jan 2, 2006")
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.3f%%", v)
+	},
+	"signed": func(v float64) string {
+		if math.Abs(v) < 0.0005 {
+			return "±0"
+		}
+		return fmt.Sprintf("%+.3f", v)
+	},
+}
+
+// WriteText renders d with the plain text template.
+func (t *Templates) WriteText(w io.Writer, d *Digest) error {
+	src := t.Text
+	if src == "" {
+		src = DefaultText
+	}
+
+	tmpl, err := texttemplate.New("text").Funcs(funcs).Parse(src)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, d)
+}
+
+// WriteHTML renders d with the HTML template.
+func (t *Templates) WriteHTML(w io.Writer, d *Digest) error {
+	src := t.HTML
+	if src == "" {
+		src = DefaultHTML
+	}
+
+	tmpl, err := htmltemplate.New("html").Funcs(funcs).Parse(src)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, d)
+}
+
+func (t *Templates) render(d *Digest) (text, html []byte, err error) {
+	var tb, hb bytes.Buffer
+	if err := t.WriteText(&tb, d); err != nil {
+		return nil, nil, err
+	}
+	if err := t.WriteHTML(&hb, d); err != nil {
+		return nil, nil, err
+	}
+	return tb.Bytes(), hb.Bytes(), nil
+}
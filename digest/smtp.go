@@ -0,0 +1,172 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Mailer sends digests over SMTP. STARTTLS is used whenever the server
+// offers it.
+type Mailer struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+
+	// Username and Password are used for PLAIN authentication when
+	// Username is set. Go only sends them over TLS or to localhost.
+	Username string
+	Password string
+
+	// From is the sender address.
+	From string
+
+	// To lists the recipient addresses.
+	To []string
+
+	// Templates used to render the digest.
+	Templates Templates
+
+	// TLSConfig is used for STARTTLS. The server name defaults to the
+	// host of Addr.
+	TLSConfig *tls.Config
+
+	// Timeout limits connecting to the server and sending the digest.
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Message returns the digest as a multipart/alternative email with a plain
+// text and an HTML part.
+func (m *Mailer) Message(d *Digest) ([]byte, error) {
+	text, html, err := m.Templates.render(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		buf bytes.Buffer
+		mw  = multipart.NewWriter(&buf)
+	)
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", d.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", d.To.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.body); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Send renders the digest and delivers it to every recipient.
+func (m *Mailer) Send(d *Digest) error {
+	if m.From == "" || len(m.To) == 0 {
+		return errors.New("digest: a sender and at least one recipient are required")
+	}
+
+	msg, err := m.Message(d)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", m.Addr, timeout)
+	if err != nil {
+		return err
+	}
+	// The whole conversation has to fit in the timeout, so a server that
+	// stops responding can't block forever.
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := &tls.Config{ServerName: host}
+		if m.TLSConfig != nil {
+			config = m.TLSConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+		}
+		if err := c.StartTLS(config); err != nil {
+			return err
+		}
+	}
+
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(m.From); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,275 @@
+// Package escalation escalates unacknowledged down webhooks through a list
+// of notification levels. The first level is notified as soon as a check
+// goes down. If nobody acknowledges the alert in time the next level is
+// notified, and so on. An "up" webhook resolves the alert and tells every
+// level that was notified.
+package escalation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// Level is a step of an escalation policy.
+type Level struct {
+	// Notifier is notified when the alert reaches this level.
+	Notifier notify.Notifier
+
+	// Wait is how long the alert may stay unacknowledged at this level
+	// before moving to the next one.
+	Wait time.Duration
+}
+
+// Alert is an open alert for a check that is down.
+type Alert struct {
+	// Hook is the webhook that opened the alert.
+	Hook observery.Webhook
+
+	// Opened is when the check went down.
+	Opened time.Time
+
+	// Level is the index of the last level notified.
+	Level int
+
+	// Escalated is when Level was notified.
+	Escalated time.Time
+
+	// Acknowledged is true once someone acknowledged the alert.
+	Acknowledged bool
+
+	// AcknowledgedBy is whoever acknowledged the alert, if given.
+	AcknowledgedBy string
+}
+
+// Escalator tracks open alerts and escalates them.
+type Escalator struct {
+	levels []Level
+	store  Store
+
+	mu     sync.Mutex
+	alerts map[string]*Alert
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// New creates an Escalator for the given levels. Open alerts are loaded
+// from store, which may be nil to keep alerts in memory only.
+func New(levels []Level, store Store) (*Escalator, error) {
+	e := &Escalator{
+		levels: levels,
+		store:  store,
+		alerts: map[string]*Alert{},
+		now:    time.Now,
+	}
+
+	if store != nil {
+		alerts, err := store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for i := range alerts {
+			e.alerts[alerts[i].Hook.CheckID] = &alerts[i]
+		}
+	}
+	return e, nil
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Escalator. Errors are passed to onError, which
+// may be nil.
+func (e *Escalator) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = e.Observe(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// Observe opens an alert and notifies the first level when a check goes
+// down, and resolves the alert when it comes back up.
+func (e *Escalator) Observe(ctx context.Context, hook *observery.Webhook) error {
+	if len(e.levels) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	alert, open := e.alerts[hook.CheckID]
+	switch {
+	case hook.State == "down" && !open:
+		now := e.now()
+		alert = &Alert{Hook: *hook, Opened: now, Escalated: now}
+		e.alerts[hook.CheckID] = alert
+	case hook.State == "up" && open:
+		delete(e.alerts, hook.CheckID)
+	default:
+		e.mu.Unlock()
+		return nil
+	}
+	err := e.save()
+	e.mu.Unlock()
+
+	if hook.State == "down" {
+		return joinErrors(err, e.levels[0].Notifier.Notify(ctx, hook))
+	}
+
+	// Tell everyone who was paged that it's over.
+	errs := []error{err}
+	for i := 0; i <= alert.Level && i < len(e.levels); i++ {
+		errs = append(errs, e.levels[i].Notifier.Notify(ctx, hook))
+	}
+	return joinErrors(errs...)
+}
+
+// Escalate notifies the next level of every unacknowledged alert whose
+// wait has expired. It is called periodically by Run.
+func (e *Escalator) Escalate(ctx context.Context) error {
+	type page struct {
+		notifier notify.Notifier
+		hook     observery.Webhook
+	}
+
+	e.mu.Lock()
+	var (
+		now   = e.now()
+		pages []page
+	)
+	for _, alert := range e.alerts {
+		if alert.Acknowledged || alert.Level+1 >= len(e.levels) {
+			continue
+		}
+		if now.Sub(alert.Escalated) < e.levels[alert.Level].Wait {
+			continue
+		}
+
+		alert.Level++
+		alert.Escalated = now
+
+		hook := alert.Hook
+		note := fmt.Sprintf("escalated to level %d, unacknowledged for %s", alert.Level+1, now.Sub(alert.Opened).Round(time.Second))
+		if hook.Details != "" {
+			hook.Details += " (" + note + ")"
+		} else {
+			hook.Details = note
+		}
+		pages = append(pages, page{e.levels[alert.Level].Notifier, hook})
+	}
+	var errs []error
+	if len(pages) > 0 {
+		errs = append(errs, e.save())
+	}
+	e.mu.Unlock()
+
+	for _, p := range pages {
+		hook := p.hook
+		errs = append(errs, p.notifier.Notify(ctx, &hook))
+	}
+	return joinErrors(errs...)
+}
+
+// Acknowledge stops the escalation of the alert for checkID. It returns
+// false if there is no open alert for the check.
+func (e *Escalator) Acknowledge(checkID, by string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alert, ok := e.alerts[checkID]
+	if !ok {
+		return false, nil
+	}
+	alert.Acknowledged = true
+	alert.AcknowledgedBy = by
+	return true, e.save()
+}
+
+// Alerts returns a copy of the open alerts ordered by when they opened.
+func (e *Escalator) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		alerts = append(alerts, *a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Opened.Before(alerts[j].Opened)
+	})
+	return alerts
+}
+
+// AckHandler returns an http.HandlerFunc that acknowledges alerts. It
+// expects a POST with the check id in the "check" form value and an
+// optional "by" value naming who acknowledged it.
+func (e *Escalator) AckHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ok, err := e.Acknowledge(r.FormValue("check"), r.FormValue("by"))
+		switch {
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		case !ok:
+			http.Error(w, "no open alert for check", http.StatusNotFound)
+		default:
+			fmt.Fprintln(w, "acknowledged")
+		}
+	}
+}
+
+// Run calls Escalate every interval until ctx is done. Errors are passed
+// to onError, which may be nil.
+func (e *Escalator) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := e.Escalate(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// save persists the open alerts. Must be called with e.mu held.
+func (e *Escalator) save() error {
+	if e.store == nil {
+		return nil
+	}
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		alerts = append(alerts, *a)
+	}
+	return e.store.Save(alerts)
+}
+
+// joinErrors combines the non-nil errors into one.
+func joinErrors(errs ...error) error {
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("escalation: %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,85 @@
+package escalation
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type recorder struct {
+	hooks []observery.Webhook
+}
+
+func (r *recorder) Notify(ctx context.Context, hook *observery.Webhook) error {
+	r.hooks = append(r.hooks, *hook)
+	return nil
+}
+
+func TestEscalation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "escalation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &FileStore{Path: filepath.Join(dir, "alerts.json")}
+
+	var (
+		ctx    = context.Background()
+		first  = &recorder{}
+		second = &recorder{}
+		levels = []Level{{Notifier: first, Wait: 10 * time.Minute}, {Notifier: second}}
+		now    = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	e, err := New(levels, store)
+	if err != nil {
+		t.Fatalf("Error creating escalator: %s\n", err)
+	}
+	e.now = func() time.Time { return now }
+
+	e.Observe(ctx, &observery.Webhook{CheckID: "a", State: "down"})
+	e.Observe(ctx, &observery.Webhook{CheckID: "b", State: "down"})
+	if len(first.hooks) != 2 || len(second.hooks) != 0 {
+		t.Fatalf("Expected only the first level to be notified but got %d and %d\n", len(first.hooks), len(second.hooks))
+	}
+
+	ts := httptest.NewServer(e.AckHandler())
+	defer ts.Close()
+	resp, err := http.PostForm(ts.URL, url.Values{"check": {"b"}, "by": {"alice"}})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unable to acknowledge: %v %v\n", resp, err)
+	}
+
+	// Simulate a restart before the wait expires.
+	e, err = New(levels, store)
+	if err != nil {
+		t.Fatalf("Error reloading escalator: %s\n", err)
+	}
+	now = now.Add(11 * time.Minute)
+	e.now = func() time.Time { return now }
+
+	if err := e.Escalate(ctx); err != nil {
+		t.Fatalf("Error escalating: %s\n", err)
+	}
+	if len(second.hooks) != 1 || second.hooks[0].CheckID != "a" {
+		t.Fatalf("Expected only a to be escalated but got %+v\n", second.hooks)
+	}
+
+	e.Observe(ctx, &observery.Webhook{CheckID: "a", State: "up"})
+	if len(first.hooks) != 3 || len(second.hooks) != 2 {
+		t.Fatalf("Expected both levels to be told about the recovery but got %d and %d\n", len(first.hooks), len(second.hooks))
+	}
+
+	alerts := e.Alerts()
+	if len(alerts) != 1 || alerts[0].Hook.CheckID != "b" || alerts[0].AcknowledgedBy != "alice" {
+		t.Fatalf("Expected only the acknowledged alert to be open but got %+v\n", alerts)
+	}
+}
@@ -0,0 +1,48 @@
+package escalation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/sfreiberg/observery/internal/atomicfile"
+)
+
+// Store persists open alerts so escalation survives restarts.
+type Store interface {
+	// Load returns the saved alerts.
+	Load() ([]Alert, error)
+
+	// Save replaces the saved alerts.
+	Save(alerts []Alert) error
+}
+
+// FileStore is a Store that keeps alerts in a JSON file.
+type FileStore struct {
+	// Path of the file.
+	Path string
+}
+
+// Load reads the alerts from the file. A missing file holds no alerts.
+func (s *FileStore) Load() ([]Alert, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []Alert
+	err = json.Unmarshal(data, &alerts)
+	return alerts, err
+}
+
+// Save atomically replaces the file with the alerts.
+func (s *FileStore) Save(alerts []Alert) error {
+	data, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0600)
+}
@@ -0,0 +1,128 @@
+// Package export writes observery outages as CSV, JSON Lines or iCalendar
+// so they can be loaded into spreadsheets, log pipelines or calendars.
+//
+// All writers take a slice of outages, such as
+// observery.ListOutagesResponse.Outages.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Columns lists every column known to WriteCSV in the default order.
+var Columns = []string{"id", "check_id", "check_name", "ongoing", "start", "stop", "duration_seconds"}
+
+// Options controls how outages are formatted. The zero value writes every
+// column with times in UTC.
+type Options struct {
+	// Columns selects and orders the CSV columns. Defaults to Columns.
+	Columns []string
+
+	// Location is the time zone times are written in. Defaults to UTC.
+	// iCalendar output is always written in UTC.
+	Location *time.Location
+
+	// Now is used as the end of ongoing outages in iCalendar output.
+	// Defaults to time.Now().
+	Now time.Time
+}
+
+func (o *Options) location() *time.Location {
+	if o == nil || o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+// formatTime formats t as RFC 3339 in the configured time zone. Zero times
+// are written as empty strings.
+func (o *Options) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(o.location()).Format(time.RFC3339)
+}
+
+// formatDuration formats d as whole seconds.
+func formatDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// WriteCSV writes outages as CSV with a header row.
+func WriteCSV(w io.Writer, outages []observery.Outage, opts *Options) error {
+	columns := Columns
+	if opts != nil && len(opts.Columns) > 0 {
+		columns = opts.Columns
+	}
+
+	fields := map[string]func(observery.Outage) string{
+		"id":               func(o observery.Outage) string { return o.ID },
+		"check_id":         func(o observery.Outage) string { return o.CheckID },
+		"check_name":       func(o observery.Outage) string { return o.CheckName },
+		"ongoing":          func(o observery.Outage) string { return strconv.FormatBool(o.Ongoing) },
+		"start":            func(o observery.Outage) string { return opts.formatTime(o.Start) },
+		"stop":             func(o observery.Outage) string { return opts.formatTime(o.Stop) },
+		"duration_seconds": func(o observery.Outage) string { return formatDuration(o.Duration) },
+	}
+	for _, c := range columns {
+		if _, ok := fields[c]; !ok {
+			return fmt.Errorf("unknown column %q", c)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, o := range outages {
+		for i, c := range columns {
+			row[i] = fields[c](o)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONLines writes one JSON object per outage and line. The keys are
+// the same as the CSV column names.
+func WriteJSONLines(w io.Writer, outages []observery.Outage, opts *Options) error {
+	type line struct {
+		ID              string `json:"id"`
+		CheckID         string `json:"check_id"`
+		CheckName       string `json:"check_name"`
+		Ongoing         bool   `json:"ongoing"`
+		Start           string `json:"start"`
+		Stop            string `json:"stop,omitempty"`
+		DurationSeconds int64  `json:"duration_seconds"`
+	}
+
+	enc := json.NewEncoder(w)
+	for _, o := range outages {
+		err := enc.Encode(line{
+			ID:              o.ID,
+			CheckID:         o.CheckID,
+			CheckName:       o.CheckName,
+			Ongoing:         o.Ongoing,
+			Start:           opts.formatTime(o.Start),
+			Stop:            opts.formatTime(o.Stop),
+			DurationSeconds: int64(o.Duration / time.Second),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
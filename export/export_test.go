@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+var outages = []observery.Outage{
+	{ID: "o1", CheckID: "c1", CheckName: "api, public", Start: time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC), Stop: time.Date(2019, 10, 1, 12, 5, 0, 0, time.UTC), Duration: 5 * time.Minute},
+	{ID: "o2", CheckID: "c2", CheckName: "db", Ongoing: true, Start: time.Date(2019, 10, 2, 8, 0, 0, 0, time.UTC)},
+}
+
+func TestWriteCSV(t *testing.T) {
+	loc := time.FixedZone("CEST", 2*60*60)
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, outages, &Options{Columns: []string{"id", "check_name", "start", "duration_seconds"}, Location: loc}); err != nil {
+		t.Fatalf("Error writing csv: %s\n", err)
+	}
+
+	want := "id,check_name,start,duration_seconds\n" +
+		"o1,\"api, public\",2019-10-01T14:00:00+02:00,300\n" +
+		"o2,db,2019-10-02T10:00:00+02:00,0\n"
+	if buf.String() != want {
+		t.Fatalf("Expected:\n%s\nGot:\n%s\n", want, buf.String())
+	}
+
+	if err := WriteCSV(&buf, outages, &Options{Columns: []string{"nope"}}); err == nil {
+		t.Fatal("Expected an error for an unknown column")
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONLines(&buf, outages, nil); err != nil {
+		t.Fatalf("Error writing json lines: %s\n", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"stop":"2019-10-01T12:05:00Z","duration_seconds":300`) {
+		t.Fatalf("Unexpected output:\n%s\n", buf.String())
+	}
+}
+
+func TestWriteICal(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2019, 10, 2, 9, 0, 0, 0, time.UTC)
+	if err := WriteICal(&buf, outages, &Options{Now: now}); err != nil {
+		t.Fatalf("Error writing icalendar: %s\n", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"UID:o1@observery.com\r\n",
+		"SUMMARY:api\\, public down\r\n",
+		"DTSTART:20191002T080000Z\r\nDTEND:20191002T090000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q\n", want)
+		}
+	}
+
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q\n", line)
+		}
+	}
+}
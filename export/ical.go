@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+const icalTime = "20060102T150405Z"
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// WriteICal writes outages as an iCalendar file with one VEVENT per outage.
+// Ongoing outages end at opts.Now.
+func WriteICal(w io.Writer, outages []observery.Outage, opts *Options) error {
+	now := time.Now()
+	if opts != nil && !opts.Now.IsZero() {
+		now = opts.Now
+	}
+	stamp := now.UTC().Format(icalTime)
+
+	bw := bufio.NewWriter(w)
+	line := func(format string, args ...interface{}) {
+		writeFolded(bw, fmt.Sprintf(format, args...))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//sfreiberg//observery//EN")
+	line("CALSCALE:GREGORIAN")
+
+	for _, o := range outages {
+		stop := o.Stop
+		if o.Ongoing || stop.IsZero() {
+			stop = now
+		}
+
+		summary := o.CheckName + " down"
+		if o.Ongoing {
+			summary += " (ongoing)"
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:%s@observery.com", o.ID)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART:%s", o.Start.UTC().Format(icalTime))
+		line("DTEND:%s", stop.UTC().Format(icalTime))
+		line("SUMMARY:%s", icalEscaper.Replace(summary))
+		line("DESCRIPTION:%s", icalEscaper.Replace(fmt.Sprintf(
+			"Check %s (%s) was down for %s seconds.", o.CheckName, o.CheckID, formatDuration(stop.Sub(o.Start)),
+		)))
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded writes a content line, folding it at 75 octets as required
+// by RFC 5545. Folds never split a UTF-8 sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		i := limit
+		for i > 0 && s[i]&0xC0 == 0x80 {
+			i--
+		}
+		w.WriteString(s[:i])
+		w.WriteString("\r\n ")
+		s = s[i:]
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,371 @@
+// Package exporter serves observery check and outage information as
+// Prometheus metrics. It writes the text exposition format directly so no
+// Prometheus client library is required.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/slo"
+	"github.com/sfreiberg/observery/store"
+)
+
+// Lister is the part of observery.Client used by the Exporter. An
+// observery.MultiClient can be used to export several accounts at once.
+type Lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+}
+
+// accountLister is implemented by observery.MultiClient. The Exporter uses
+// it to tell which accounts failed to refresh.
+type accountLister interface {
+	Accounts() []string
+	FetchChecks(ctx context.Context) ([]observery.Check, error)
+	FetchOutages(ctx context.Context) ([]observery.Outage, error)
+}
+
+// Exporter is an http.Handler that serves metrics about checks and outages.
+// API results are cached for CacheTTL so frequent scrapes don't exceed the
+// observery API limits.
+//
+// When the client is an observery.MultiClient the refresh of every account
+// is reported through observery_account_scrape_success, and accounts that
+// fail keep their previous results instead of disappearing.
+type Exporter struct {
+	// CacheTTL is how long API results are reused between scrapes.
+	// Defaults to one minute.
+	CacheTTL time.Duration
+
+	// Timeout limits how long refreshing from the API may take. Defaults to
+	// 30 seconds.
+	Timeout time.Duration
+
+	// Objectives are SLOs whose error budgets and burn rates are served
+	// as metrics. Optional.
+	Objectives []slo.Objective
+
+	// History is the outage history SLOs are computed from. The most
+	// recent outages returned by the API are used when it is nil, which
+	// undercounts downtime once there are more than 100 outages within an
+	// objective's window.
+	History store.Store
+
+	client Lister
+
+	mu        sync.Mutex
+	fetched   time.Time
+	checks    []observery.Check
+	outages   []observery.Outage
+	succeeded bool
+	latency   time.Duration
+
+	// accounts holds whether the last refresh of each account succeeded.
+	accounts map[string]bool
+}
+
+// New creates an Exporter that reads from client.
+func New(client Lister) *Exporter {
+	return &Exporter{
+		CacheTTL: time.Minute,
+		Timeout:  30 * time.Second,
+		client:   client,
+	}
+}
+
+// ServeHTTP writes the current metrics, refreshing them from the API if the
+// cache has expired. OpenMetrics is served when the scraper asks for it.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	t, contentType := newTextWriter(&buf, r)
+	if err := e.write(r.Context(), t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// WriteMetrics writes the current metrics to w in the Prometheus text
+// format, refreshing them from the API if the cache has expired. A failed
+// refresh is reported through the observery_scrape_success metric and the
+// previous results are kept.
+func (e *Exporter) WriteMetrics(ctx context.Context, w io.Writer) error {
+	return e.write(ctx, &textWriter{w: w})
+}
+
+func (e *Exporter) write(ctx context.Context, t *textWriter) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if time.Since(e.fetched) >= e.CacheTTL {
+		e.refresh(ctx)
+	}
+
+	now := time.Now()
+
+	t.header("observery_scrape_success", "Whether the last refresh from the observery API succeeded.", "gauge")
+	t.sample("observery_scrape_success", boolValue(e.succeeded))
+
+	if la, ok := e.client.(accountLister); ok {
+		t.header("observery_account_scrape_success", "Whether the last refresh of the account from the observery API succeeded.", "gauge")
+		for _, a := range la.Accounts() {
+			t.sample("observery_account_scrape_success", boolValue(e.accounts[a]), "account", a)
+		}
+	}
+
+	t.header("observery_scrape_duration_seconds", "How long the last refresh from the observery API took.", "gauge")
+	t.sample("observery_scrape_duration_seconds", e.latency.Seconds())
+
+	t.header("observery_scrape_timestamp_seconds", "When the cached results were last refreshed.", "gauge")
+	t.sample("observery_scrape_timestamp_seconds", unixSeconds(e.fetched))
+
+	t.header("observery_check_up", "Whether the check is up (1) or not (0).", "gauge")
+	for _, c := range e.checks {
+		t.sample("observery_check_up", boolValue(c.State == "up"), checkLabels(c)...)
+	}
+
+	t.header("observery_check_active", "Whether the check is active.", "gauge")
+	for _, c := range e.checks {
+		t.sample("observery_check_active", boolValue(c.Active), checkLabels(c)...)
+	}
+
+	t.header("observery_check_state_since_seconds", "Unix time of the last state change of the check.", "gauge")
+	for _, c := range e.checks {
+		if !c.Since.IsZero() {
+			t.sample("observery_check_state_since_seconds", unixSeconds(c.Since), checkLabels(c)...)
+		}
+	}
+
+	ongoing := 0
+	t.header("observery_outage_duration_seconds", "Duration of the most recent outages. Ongoing outages are measured up to now.", "gauge")
+	for _, o := range e.outages {
+		duration := o.Duration
+		if o.Ongoing {
+			ongoing++
+			duration = now.Sub(o.Start)
+		}
+		labels := []string{
+			"id", o.ID,
+			"check_id", o.CheckID,
+			"check_name", o.CheckName,
+			"ongoing", strconv.FormatBool(o.Ongoing),
+		}
+		if o.Account != "" {
+			labels = append(labels, "account", o.Account)
+		}
+		t.sample("observery_outage_duration_seconds", duration.Seconds(), labels...)
+	}
+
+	t.header("observery_ongoing_outages", "Number of outages that are currently ongoing.", "gauge")
+	t.sample("observery_ongoing_outages", float64(ongoing))
+
+	if len(e.Objectives) > 0 {
+		if err := e.writeObjectives(t, now); err != nil {
+			return err
+		}
+	}
+
+	t.finish()
+	return t.err
+}
+
+// writeObjectives writes the SLO metrics. Must be called with e.mu held.
+func (e *Exporter) writeObjectives(t *textWriter, now time.Time) error {
+	outages := e.outages
+	if e.History != nil {
+		var err error
+		if outages, err = e.History.Range(time.Time{}, now); err != nil {
+			return err
+		}
+	}
+
+	budgets := make([]slo.Budget, len(e.Objectives))
+	for i, o := range e.Objectives {
+		budgets[i] = slo.Compute(o, outages, now)
+	}
+
+	t.header("observery_slo_target_ratio", "Availability target of the objective.", "gauge")
+	for _, b := range budgets {
+		t.sample("observery_slo_target_ratio", b.Target/100, "objective", b.Objective)
+	}
+
+	t.header("observery_slo_availability_ratio", "Availability of the objective within its window.", "gauge")
+	for _, b := range budgets {
+		t.sample("observery_slo_availability_ratio", b.Availability/100, "objective", b.Objective)
+	}
+
+	t.header("observery_slo_error_budget_remaining_seconds", "Downtime left before the objective misses its target.", "gauge")
+	for _, b := range budgets {
+		t.sample("observery_slo_error_budget_remaining_seconds", b.Remaining.Seconds(), "objective", b.Objective)
+	}
+
+	t.header("observery_slo_error_budget_remaining_ratio", "Remaining error budget as a fraction of the allowed downtime.", "gauge")
+	for _, b := range budgets {
+		t.sample("observery_slo_error_budget_remaining_ratio", b.RemainingRatio, "objective", b.Objective)
+	}
+
+	t.header("observery_slo_burn_rate", "Rate at which the error budget is spent over the window, 1 being sustainable.", "gauge")
+	for _, o := range e.Objectives {
+		windows := map[slo.Duration]bool{}
+		alerts := o.BurnAlerts
+		if len(alerts) == 0 {
+			alerts = slo.DefaultBurnAlerts
+		}
+		for _, b := range alerts {
+			for _, w := range []slo.Duration{b.Short, b.Long} {
+				if windows[w] {
+					continue
+				}
+				windows[w] = true
+				rate := slo.BurnRate(o, outages, time.Duration(w), now)
+				t.sample("observery_slo_burn_rate", rate, "objective", o.Name, "window", w.String())
+			}
+		}
+	}
+	return nil
+}
+
+// refresh reloads checks and outages from the API. Must be called with e.mu
+// held.
+func (e *Exporter) refresh(ctx context.Context) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var (
+		checks  []observery.Check
+		outages []observery.Outage
+		err     error
+	)
+	if la, ok := e.client.(accountLister); ok {
+		checks, outages, err = e.fetchAccounts(ctx, la)
+	} else {
+		checks, outages, err = fetch(ctx, e.client)
+	}
+	e.latency = time.Since(start)
+	e.fetched = time.Now()
+	e.succeeded = err == nil
+	if err != nil {
+		return
+	}
+
+	e.checks = checks
+	e.outages = outages
+}
+
+// fetchAccounts fetches checks and outages of every account. Accounts that
+// fail keep their previous checks and outages, and make the refresh fail
+// as a whole. Must be called with e.mu held.
+func (e *Exporter) fetchAccounts(ctx context.Context, client accountLister) ([]observery.Check, []observery.Outage, error) {
+	accounts := client.Accounts()
+	e.accounts = make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		e.accounts[a] = true
+	}
+
+	checks, checksErr := client.FetchChecks(ctx)
+	outages, outagesErr := client.FetchOutages(ctx)
+
+	var first error
+	for _, err := range []error{checksErr, outagesErr} {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		var failed observery.MultiError
+		if !errors.As(err, &failed) {
+			// Not tied to an account, so nothing can be trusted.
+			for _, a := range accounts {
+				e.accounts[a] = false
+			}
+			continue
+		}
+		for _, f := range failed {
+			e.accounts[f.Account] = false
+		}
+	}
+	if first == nil {
+		return checks, outages, nil
+	}
+
+	// Only keep the new results of accounts that fully refreshed.
+	var (
+		keepChecks  []observery.Check
+		keepOutages []observery.Outage
+	)
+	for _, c := range checks {
+		if e.accounts[c.Account] {
+			keepChecks = append(keepChecks, c)
+		}
+	}
+	for _, c := range e.checks {
+		if !e.accounts[c.Account] {
+			keepChecks = append(keepChecks, c)
+		}
+	}
+	for _, o := range outages {
+		if e.accounts[o.Account] {
+			keepOutages = append(keepOutages, o)
+		}
+	}
+	for _, o := range e.outages {
+		if !e.accounts[o.Account] {
+			keepOutages = append(keepOutages, o)
+		}
+	}
+	e.checks = keepChecks
+	e.outages = keepOutages
+	return nil, nil, first
+}
+
+func fetch(ctx context.Context, client Lister) ([]observery.Check, []observery.Outage, error) {
+	checks, err := client.ListChecks(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !checks.Success {
+		return nil, nil, errors.New(checks.Reason)
+	}
+
+	outages, err := client.ListOutages(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !outages.Success {
+		return nil, nil, errors.New(outages.Reason)
+	}
+
+	return checks.Checks, outages.Outages, nil
+}
+
+// checkLabels returns the labels of a check. The account label is only
+// added for checks listed through an observery.MultiClient.
+func checkLabels(c observery.Check) []string {
+	labels := []string{"id", c.ID, "name", c.Name, "type", c.Type}
+	if c.Account != "" {
+		labels = append(labels, "account", c.Account)
+	}
+	return labels
+}
+
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/slo"
+)
+
+type fakeLister struct {
+	checks  *observery.ListChecksResponse
+	outages *observery.ListOutagesResponse
+	calls   int
+}
+
+func (f *fakeLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	f.calls++
+	return f.checks, nil
+}
+
+func (f *fakeLister) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return f.outages, nil
+}
+
+func TestExporter(t *testing.T) {
+	since := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	lister := &fakeLister{
+		checks: &observery.ListChecksResponse{
+			Success: true,
+			Checks: []observery.Check{
+				{ID: "1", Name: `Say "hi"`, Type: "http", Active: true, State: "up", Since: since},
+				{ID: "2", Name: "db", Type: "ping", Active: true, State: "down", Since: since},
+			},
+		},
+		outages: &observery.ListOutagesResponse{
+			Success: true,
+			Outages: []observery.Outage{
+				{ID: "o1", CheckID: "2", CheckName: "db", Ongoing: true, Start: time.Now().Add(-time.Minute)},
+				{ID: "o2", CheckID: "1", CheckName: "web", Duration: 90 * time.Second},
+			},
+		},
+	}
+
+	e := New(lister)
+	e.Objectives = []slo.Objective{{Name: "database", Checks: []string{"db"}, Target: 99.9}}
+	var buf bytes.Buffer
+	if err := e.WriteMetrics(context.Background(), &buf); err != nil {
+		t.Fatalf("Error writing metrics: %s\n", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"observery_scrape_success 1\n",
+		`observery_check_up{id="1",name="Say \"hi\"",type="http"} 1` + "\n",
+		`observery_check_up{id="2",name="db",type="ping"} 0` + "\n",
+		`observery_check_state_since_seconds{id="2",name="db",type="ping"} 1.5699312e+09` + "\n",
+		`observery_outage_duration_seconds{id="o2",check_id="1",check_name="web",ongoing="false"} 90` + "\n",
+		"observery_ongoing_outages 1\n",
+		`observery_slo_error_budget_remaining_ratio{objective="database"} 0.9`,
+		`observery_slo_burn_rate{objective="database",window="5m"} 200`,
+		`observery_slo_burn_rate{objective="database",window="6h"} 2.7`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q\n", want)
+		}
+	}
+
+	buf.Reset()
+	e.WriteMetrics(context.Background(), &buf)
+	if lister.calls != 1 {
+		t.Fatalf("Expected cached results but the API was called %d times\n", lister.calls)
+	}
+}
+
+func TestWebhookMetrics(t *testing.T) {
+	m := &WebhookMetrics{Buckets: []float64{1, 0.1, 1}, MaxChecks: 1}
+	m.Observe(&observery.Webhook{CheckID: "1", CheckName: "web", CheckType: "http", State: "down", HTTPStatusCode: 500, ResponseTime: 2 * time.Second})
+
+	// Buckets are fixed by the first webhook.
+	m.Buckets = append(m.Buckets, 5)
+	m.Observe(&observery.Webhook{CheckID: "1", CheckName: "web", CheckType: "http", State: "up", HTTPStatusCode: 200, ResponseTime: 50 * time.Millisecond})
+	m.Observe(&observery.Webhook{CheckID: "2", CheckName: "db", CheckType: "ping", State: "down", TimedOut: true})
+
+	var buf bytes.Buffer
+	if err := m.WriteMetrics(&buf); err != nil {
+		t.Fatalf("Error writing metrics: %s\n", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`observery_webhook_response_time_seconds_bucket{id="1",name="web",type="http",le="0.1"} 1` + "\n",
+		`observery_webhook_response_time_seconds_bucket{id="1",name="web",type="http",le="1"} 1` + "\n",
+		`observery_webhook_response_time_seconds_bucket{id="1",name="web",type="http",le="+Inf"} 2` + "\n",
+		`observery_webhook_response_time_seconds_sum{id="1",name="web",type="http"} 2.05` + "\n",
+		`observery_webhook_http_status_total{id="1",name="web",type="http",code="500"} 1` + "\n",
+		`observery_webhook_transitions_total{id="1",name="web",type="http",state="up"} 1` + "\n",
+		`observery_webhook_timeouts_total{id="other",name="other",type="other"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q\n", want)
+		}
+	}
+	if strings.Contains(out, `state="down"`) {
+		t.Errorf("Expected the first state of a check not to count as a transition:\n%s", out)
+	}
+	if strings.Contains(out, `le="5"`) || strings.Count(out, `le="1"`) != 2 || strings.Index(out, `le="1"`) < strings.Index(out, `le="0.1"`) {
+		t.Errorf("Expected the buckets of the first webhook, sorted and unique:\n%s", out)
+	}
+}
+
+// accountsTransport fakes the observery API for a MultiClient. Every
+// account uses its name as username and can be made to fail.
+type accountsTransport struct {
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func (f *accountsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	account, _, _ := req.BasicAuth()
+	f.mu.Lock()
+	failed := f.failed[account]
+	f.mu.Unlock()
+	if failed {
+		return nil, errors.New("connection refused")
+	}
+
+	body := `{"success": true, "result": []}`
+	if strings.HasSuffix(req.URL.Path, "/check") {
+		body = `{"success": true, "result": [{"id": "1", "name": "` + account + `", "type": "http", "state": "up"}]}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestExporterAccounts(t *testing.T) {
+	transport := &accountsTransport{failed: map[string]bool{}}
+	defer func(rt http.RoundTripper) { http.DefaultTransport = rt }(http.DefaultTransport)
+	http.DefaultTransport = transport
+
+	client, err := observery.NewMultiClient(map[string]*observery.Client{
+		"prod":    observery.NewClient("prod", "secret"),
+		"staging": observery.NewClient("staging", "secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(client)
+
+	scrape := func() string {
+		e.mu.Lock()
+		e.fetched = time.Time{}
+		e.mu.Unlock()
+
+		var buf bytes.Buffer
+		if err := e.WriteMetrics(context.Background(), &buf); err != nil {
+			t.Fatalf("Error writing metrics: %s\n", err)
+		}
+		return buf.String()
+	}
+
+	scrape()
+	transport.mu.Lock()
+	transport.failed["staging"] = true
+	transport.mu.Unlock()
+	out := scrape()
+
+	for _, want := range []string{
+		"observery_scrape_success 0\n",
+		`observery_account_scrape_success{account="prod"} 1` + "\n",
+		`observery_account_scrape_success{account="staging"} 0` + "\n",
+		`observery_check_up{id="prod/1",name="prod",type="http",account="prod"} 1` + "\n",
+		`observery_check_up{id="staging/1",name="staging",type="http",account="staging"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q\n", want)
+		}
+	}
+}
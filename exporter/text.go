@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// labelEscaper escapes label values as required by the Prometheus text
+// exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+const (
+	prometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// textWriter writes metrics in the Prometheus text exposition format, or
+// the OpenMetrics text format when openMetrics is set. The first write
+// error is kept and all later writes are skipped.
+type textWriter struct {
+	w           io.Writer
+	openMetrics bool
+	err         error
+}
+
+// newTextWriter returns a textWriter using the format requested in the
+// Accept header of r, along with the matching content type.
+func newTextWriter(w io.Writer, r *http.Request) (*textWriter, string) {
+	if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+		return &textWriter{w: w, openMetrics: true}, openMetricsContentType
+	}
+	return &textWriter{w: w}, prometheusContentType
+}
+
+// header writes the HELP and TYPE lines of a metric family. OpenMetrics
+// names counter families without the _total suffix.
+func (t *textWriter) header(name, help, typ string) {
+	if t.openMetrics && typ == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	t.printf("# HELP %s %s\n# TYPE %s %s\n", name, strings.Replace(help, "\n", " ", -1), name, typ)
+}
+
+// sample writes a single sample. labels is a list of name/value pairs.
+func (t *textWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(labelEscaper.Replace(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	t.printf("%s %s\n", b.String(), formatValue(value))
+}
+
+// finish terminates the exposition. Only OpenMetrics requires this.
+func (t *textWriter) finish() {
+	if t.openMetrics {
+		t.printf("# EOF\n")
+	}
+}
+
+func (t *textWriter) printf(format string, args ...interface{}) {
+	if t.err != nil {
+		return
+	}
+	_, t.err = fmt.Fprintf(t.w, format, args...)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,228 @@
+package exporter
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/sfreiberg/observery"
+)
+
+// DefaultBuckets are the response time histogram buckets, in seconds, used
+// when WebhookMetrics.Buckets is empty.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// otherCheck is the label value used for checks over WebhookMetrics.MaxChecks.
+const otherCheck = "other"
+
+// WebhookMetrics aggregates incoming observery webhooks per check and serves
+// them as metrics. Webhooks are the only place observery reports response
+// times, status codes and timeouts, so these metrics complement the ones
+// served by the Exporter.
+type WebhookMetrics struct {
+	// Buckets are the upper bounds, in seconds, of the response time
+	// histogram. They are sorted, and duplicates, NaN and infinite bounds
+	// are dropped. Changing them after the first webhook or scrape has no
+	// effect.
+	Buckets []float64
+
+	// MaxChecks limits the number of checks that get their own labels.
+	// Webhooks for any further checks are counted with the id, name and
+	// type labels set to "other". Zero means no limit.
+	MaxChecks int
+
+	once    sync.Once
+	buckets []float64
+
+	mu     sync.Mutex
+	checks map[string]*checkMetrics
+}
+
+type checkMetrics struct {
+	id, name, typ string
+
+	// counts holds the cumulative count for every bucket in order.
+	counts []uint64
+	sum    float64
+	count  uint64
+
+	statusCodes map[int]uint64
+	timeouts    uint64
+	transitions map[string]uint64
+	state       string
+}
+
+// Handler returns an http.HandlerFunc that records every webhook sent by
+// observery.com. Webhooks that can't be decoded are ignored.
+func (m *WebhookMetrics) Handler() http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			m.Observe(hook)
+		}
+	})
+}
+
+// Observe records a single webhook.
+func (m *WebhookMetrics) Observe(hook *observery.Webhook) {
+	buckets := m.bounds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.checks == nil {
+		m.checks = map[string]*checkMetrics{}
+	}
+
+	id, name, typ := hook.CheckID, hook.CheckName, hook.CheckType
+	if _, ok := m.checks[id]; !ok && m.MaxChecks > 0 && len(m.checks) >= m.MaxChecks {
+		id, name, typ = otherCheck, otherCheck, otherCheck
+	}
+
+	c, ok := m.checks[id]
+	if !ok {
+		c = &checkMetrics{
+			id:          id,
+			typ:         typ,
+			counts:      make([]uint64, len(buckets)),
+			statusCodes: map[int]uint64{},
+			transitions: map[string]uint64{},
+		}
+		m.checks[id] = c
+	}
+	c.name = name
+
+	seconds := hook.ResponseTime.Seconds()
+	for i, bound := range buckets {
+		if seconds <= bound {
+			c.counts[i]++
+		}
+	}
+	c.sum += seconds
+	c.count++
+
+	if hook.HTTPStatusCode != 0 {
+		c.statusCodes[hook.HTTPStatusCode]++
+	}
+	if hook.TimedOut {
+		c.timeouts++
+	}
+	// The first state seen for a check, for example after a restart, is
+	// recorded without counting it as a transition.
+	if c.state != "" && hook.State != c.state {
+		c.transitions[hook.State]++
+	}
+	c.state = hook.State
+}
+
+// ServeHTTP writes the aggregated metrics. OpenMetrics is served when the
+// scraper asks for it.
+func (m *WebhookMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	t, contentType := newTextWriter(&buf, r)
+	if err := m.write(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+// WriteMetrics writes the aggregated metrics to w in the Prometheus text
+// format.
+func (m *WebhookMetrics) WriteMetrics(w io.Writer) error {
+	return m.write(&textWriter{w: w})
+}
+
+func (m *WebhookMetrics) write(t *textWriter) error {
+	buckets := m.bounds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.checks))
+	for id := range m.checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	const histogram = "observery_webhook_response_time_seconds"
+	t.header(histogram, "Response times reported by observery webhooks.", "histogram")
+	for _, id := range ids {
+		c := m.checks[id]
+		for i, bound := range buckets {
+			t.sample(histogram+"_bucket", float64(c.counts[i]), append(c.labels(), "le", formatValue(bound))...)
+		}
+		t.sample(histogram+"_bucket", float64(c.count), append(c.labels(), "le", "+Inf")...)
+		t.sample(histogram+"_sum", c.sum, c.labels()...)
+		t.sample(histogram+"_count", float64(c.count), c.labels()...)
+	}
+
+	t.header("observery_webhook_http_status_total", "HTTP status codes reported by observery webhooks.", "counter")
+	for _, id := range ids {
+		c := m.checks[id]
+		codes := make([]int, 0, len(c.statusCodes))
+		for code := range c.statusCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			t.sample("observery_webhook_http_status_total", float64(c.statusCodes[code]), append(c.labels(), "code", strconv.Itoa(code))...)
+		}
+	}
+
+	t.header("observery_webhook_timeouts_total", "Checks that timed out according to observery webhooks.", "counter")
+	for _, id := range ids {
+		c := m.checks[id]
+		t.sample("observery_webhook_timeouts_total", float64(c.timeouts), c.labels()...)
+	}
+
+	t.header("observery_webhook_transitions_total", "State transitions reported by observery webhooks, by new state.", "counter")
+	for _, id := range ids {
+		c := m.checks[id]
+		states := make([]string, 0, len(c.transitions))
+		for state := range c.transitions {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		for _, state := range states {
+			t.sample("observery_webhook_transitions_total", float64(c.transitions[state]), append(c.labels(), "state", state)...)
+		}
+	}
+
+	t.finish()
+	return t.err
+}
+
+// bounds returns the histogram buckets, taken from Buckets on first use.
+func (m *WebhookMetrics) bounds() []float64 {
+	m.once.Do(func() {
+		buckets := m.Buckets
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+
+		// Prometheus requires ascending, unique le values and adds +Inf
+		// itself.
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		for _, b := range sorted {
+			if math.IsNaN(b) || math.IsInf(b, 0) {
+				continue
+			}
+			if n := len(m.buckets); n > 0 && m.buckets[n-1] == b {
+				continue
+			}
+			m.buckets = append(m.buckets, b)
+		}
+	})
+	return m.buckets
+}
+
+func (c *checkMetrics) labels() []string {
+	return []string{"id", c.id, "name", c.name, "type", c.typ}
+}
@@ -0,0 +1,226 @@
+// Package flap suppresses notifications for checks that keep bouncing
+// between up and down. A Detector sits in front of a notifier, keeps a
+// sliding window of state changes per check and, once a check flaps,
+// replaces its notifications with a single "flapping" notification and a
+// "stabilized" one when it calms down.
+package flap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// Thresholds decide when a check is flapping.
+type Thresholds struct {
+	// Window is how far back state changes are counted. Defaults to one
+	// hour.
+	Window time.Duration
+
+	// FlapAt is the number of state changes within Window at which a
+	// check is considered flapping. Defaults to 5.
+	FlapAt int
+
+	// StableAt is the number of state changes within Window at or below
+	// which a flapping check is considered stable again. Defaults to half
+	// of FlapAt.
+	StableAt int
+}
+
+func (t Thresholds) withDefaults() Thresholds {
+	if t.Window == 0 {
+		t.Window = time.Hour
+	}
+	if t.FlapAt == 0 {
+		t.FlapAt = 5
+	}
+	if t.StableAt == 0 {
+		t.StableAt = t.FlapAt / 2
+	}
+	return t
+}
+
+// Detector is a notify.Notifier that forwards webhooks to Next unless the
+// check is flapping.
+type Detector struct {
+	// Next receives the webhooks of checks that aren't flapping, as well
+	// as the "flapping" and "stabilized" notifications.
+	Next notify.Notifier
+
+	// Default holds the thresholds for checks without their own.
+	Default Thresholds
+
+	// Checks holds thresholds per check id or name.
+	Checks map[string]Thresholds
+
+	mu     sync.Mutex
+	checks map[string]*history
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+type history struct {
+	hook     observery.Webhook
+	changes  []time.Time
+	flapping bool
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Detector. Errors are passed to onError, which may
+// be nil.
+func (d *Detector) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = d.Notify(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// ObserveStateChange feeds an event from Client.WatchChecks into the
+// Detector.
+func (d *Detector) ObserveStateChange(ctx context.Context, e observery.StateChangeEvent) error {
+	return d.Notify(ctx, e.Webhook())
+}
+
+// Notify records the state change and forwards hook to Next, unless the
+// check is flapping. When the check starts flapping a single "flapping"
+// notification is sent instead.
+func (d *Detector) Notify(ctx context.Context, hook *observery.Webhook) error {
+	d.mu.Lock()
+	if d.checks == nil {
+		d.checks = map[string]*history{}
+	}
+
+	var (
+		now        = d.clock()
+		thresholds = d.thresholds(hook)
+		h, ok      = d.checks[hook.CheckID]
+	)
+	if !ok {
+		h = &history{}
+		d.checks[hook.CheckID] = h
+	}
+
+	changed := !ok || h.hook.State != hook.State
+	h.hook = *hook
+	if changed {
+		h.changes = append(h.changes, now)
+	}
+	h.prune(now, thresholds.Window)
+
+	var send *observery.Webhook
+	switch {
+	case h.flapping:
+		// Suppressed until it stabilizes.
+	case len(h.changes) >= thresholds.FlapAt:
+		h.flapping = true
+		send = summary(hook, "flapping", fmt.Sprintf("%d state changes in %s", len(h.changes), thresholds.Window))
+	default:
+		send = hook
+	}
+	d.mu.Unlock()
+
+	if send == nil {
+		return nil
+	}
+	return d.Next.Notify(ctx, send)
+}
+
+// Flapping reports whether the check is currently flapping.
+func (d *Detector) Flapping(checkID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	h, ok := d.checks[checkID]
+	return ok && h.flapping
+}
+
+// Stabilize sends a "stabilized" notification, carrying the current state
+// in Details, for every flapping check whose state changes dropped to
+// Thresholds.StableAt. It is called periodically by Run.
+func (d *Detector) Stabilize(ctx context.Context) error {
+	d.mu.Lock()
+	var (
+		now   = d.clock()
+		sends []*observery.Webhook
+	)
+	for _, h := range d.checks {
+		if !h.flapping {
+			continue
+		}
+		thresholds := d.thresholds(&h.hook)
+		h.prune(now, thresholds.Window)
+		if len(h.changes) <= thresholds.StableAt {
+			h.flapping = false
+			sends = append(sends, summary(&h.hook, "stabilized", "currently "+h.hook.State))
+		}
+	}
+	d.mu.Unlock()
+
+	var first error
+	for _, hook := range sends {
+		if err := d.Next.Notify(ctx, hook); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Run calls Stabilize every interval until ctx is done. Errors are passed
+// to onError, which may be nil.
+func (d *Detector) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := d.Stabilize(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (d *Detector) thresholds(hook *observery.Webhook) Thresholds {
+	if t, ok := d.Checks[hook.CheckID]; ok {
+		return t.withDefaults()
+	}
+	if t, ok := d.Checks[hook.CheckName]; ok {
+		return t.withDefaults()
+	}
+	return d.Default.withDefaults()
+}
+
+func (d *Detector) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// prune drops state changes older than window.
+func (h *history) prune(now time.Time, window time.Duration) {
+	i := 0
+	for i < len(h.changes) && now.Sub(h.changes[i]) > window {
+		i++
+	}
+	h.changes = h.changes[i:]
+}
+
+// summary returns a copy of hook with the given state and details.
+func summary(hook *observery.Webhook, state, details string) *observery.Webhook {
+	s := *hook
+	s.State = state
+	s.Details = details
+	return &s
+}
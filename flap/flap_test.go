@@ -0,0 +1,66 @@
+package flap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type recorder struct {
+	states []string
+}
+
+func (r *recorder) Notify(ctx context.Context, hook *observery.Webhook) error {
+	r.states = append(r.states, hook.State)
+	return nil
+}
+
+func TestDetector(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		next = &recorder{}
+		now  = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+		d    = &Detector{
+			Next:    next,
+			Default: Thresholds{Window: 10 * time.Minute, FlapAt: 4},
+			Checks:  map[string]Thresholds{"stable": {FlapAt: 100}},
+		}
+	)
+	d.now = func() time.Time { return now }
+
+	for i, state := range []string{"down", "up", "down", "up", "down", "up"} {
+		now = now.Add(time.Minute)
+		d.Notify(ctx, &observery.Webhook{CheckID: "a", State: state})
+		d.Notify(ctx, &observery.Webhook{CheckID: "b", CheckName: "stable", State: state})
+		if i == 3 && !d.Flapping("a") {
+			t.Fatal("Expected a to be flapping after 4 changes")
+		}
+	}
+
+	// a: three changes get through, the fourth becomes the summary and the
+	// rest are suppressed. b: everything gets through.
+	want := []string{"down", "down", "up", "up", "down", "down", "flapping", "up", "down", "up"}
+	if len(next.states) != len(want) {
+		t.Fatalf("Expected %v but got %v\n", want, next.states)
+	}
+	for i := range want {
+		if next.states[i] != want[i] {
+			t.Fatalf("Expected %v but got %v\n", want, next.states)
+		}
+	}
+
+	next.states = nil
+	now = now.Add(5 * time.Minute)
+	d.Stabilize(ctx)
+	if len(next.states) != 0 {
+		t.Fatalf("Expected a to still be flapping but got %v\n", next.states)
+	}
+
+	now = now.Add(5 * time.Minute)
+	d.Stabilize(ctx)
+	if len(next.states) != 1 || next.states[0] != "stabilized" || d.Flapping("a") {
+		t.Fatalf("Expected a to stabilize but got %v\n", next.states)
+	}
+}
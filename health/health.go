@@ -0,0 +1,304 @@
+// Package health provides an http.Handler that services can mount as a
+// dependency endpoint. It reports the observery state of the upstream
+// checks a service relies on as JSON, and answers 200 or 503 depending on
+// a policy, so load balancers and orchestrators can act on it.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Lister is the part of observery.Client used by the Handler.
+type Lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+}
+
+// Policy decides when the dependencies as a whole are up.
+type Policy string
+
+// The supported policies.
+const (
+	// All requires every check to be up.
+	All Policy = "all"
+
+	// Any requires at least one check to be up.
+	Any Policy = "any"
+
+	// Quorum requires Options.Quorum checks to be up.
+	Quorum Policy = "quorum"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// Checks lists check ids or names to include. A listed check that
+	// doesn't exist counts as down.
+	Checks []string
+
+	// Selector includes every check it matches, like "type=http,name=db-*".
+	// All checks are included when neither Checks nor Selector are set.
+	Selector string
+
+	// Policy defaults to All.
+	Policy Policy
+
+	// Quorum is the number of checks that must be up for the Quorum
+	// policy. Defaults to a majority.
+	Quorum int
+
+	// CacheTTL is how long a summary is reused. Defaults to 30 seconds. A
+	// negative value refreshes on every request.
+	CacheTTL time.Duration
+
+	// Timeout limits how long refreshing from the API may take. Defaults
+	// to 10 seconds.
+	Timeout time.Duration
+}
+
+// Summary is the JSON document served by the Handler.
+type Summary struct {
+	// Status is "up" or "down" according to the policy, or "unknown" if
+	// the API was never reached, some accounts of a MultiClient couldn't
+	// be listed or no active check was selected.
+	Status string `json:"status"`
+
+	// Policy that decided Status.
+	Policy Policy `json:"policy"`
+
+	// Up and Total count the active checks that are up and the ones that
+	// were evaluated.
+	Up    int `json:"up"`
+	Total int `json:"total"`
+
+	// Checks lists every included check.
+	Checks []Check `json:"checks"`
+
+	// CheckedAt is when the summary was fetched from the API.
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+
+	// Stale is true when the last refresh failed and an older summary is
+	// served. Error holds the reason.
+	Stale bool   `json:"stale"`
+	Error string `json:"error,omitempty"`
+}
+
+// Check is the state of a single dependency. Inactive checks are listed
+// but not evaluated.
+type Check struct {
+	ID     string    `json:"id,omitempty"`
+	Name   string    `json:"name"`
+	State  string    `json:"state"`
+	Active bool      `json:"active"`
+	Since  time.Time `json:"since,omitempty"`
+}
+
+// Handler serves the Summary of a set of checks.
+type Handler struct {
+	client   Lister
+	opts     Options
+	selector observery.Selector
+
+	mu        sync.Mutex
+	summary   *Summary
+	attempted time.Time
+
+	// refreshing is closed when the running refresh is done. Nil if none
+	// is running.
+	refreshing chan struct{}
+}
+
+// NewHandler creates a Handler for the checks selected by opts. It returns
+// an error if the selector or policy is invalid.
+func NewHandler(client Lister, opts Options) (*Handler, error) {
+	sel, err := observery.ParseSelector(opts.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Policy {
+	case "":
+		opts.Policy = All
+	case All, Any, Quorum:
+	default:
+		return nil, errors.New("health: unknown policy " + string(opts.Policy))
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	return &Handler{client: client, opts: opts, selector: sel}, nil
+}
+
+// ServeHTTP writes the Summary with status 200 when the dependencies are up
+// and 503 otherwise.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := h.Summary(r.Context())
+
+	code := http.StatusOK
+	if s.Status != "up" {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(s)
+}
+
+// Summary returns the current summary, refreshing it from the API if the
+// cache has expired. When the refresh fails the previous summary is
+// returned marked as stale. Failed refreshes are cached too, so an
+// unreachable API isn't hit on every request.
+//
+// Only one refresh runs at a time. The request that started it waits for
+// it, while concurrent requests get the previous summary right away so a
+// slow API doesn't hold up every probe.
+func (h *Handler) Summary(ctx context.Context) Summary {
+	h.mu.Lock()
+	if h.summary != nil && time.Since(h.attempted) < h.opts.CacheTTL {
+		s := *h.summary
+		h.mu.Unlock()
+		return s
+	}
+
+	done := h.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		h.refreshing = done
+		h.attempted = time.Now()
+		go h.refresh(done)
+	} else if h.summary != nil {
+		s := *h.summary
+		h.mu.Unlock()
+		return s
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return Summary{Status: "unknown", Policy: h.opts.Policy, Checks: []Check{}, Stale: true, Error: ctx.Err().Error()}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return *h.summary
+}
+
+// refresh fetches the checks and updates the summary, then closes done.
+// It isn't bound to a request so an impatient client can't cancel the
+// refresh other requests wait for.
+func (h *Handler) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.Timeout)
+	defer cancel()
+
+	resp, err := h.client.ListChecks(ctx)
+	if err == nil && !resp.Success {
+		err = errors.New(resp.Reason)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	defer close(done)
+	h.refreshing = nil
+
+	if err != nil {
+		if h.summary == nil {
+			h.summary = &Summary{Status: "unknown", Policy: h.opts.Policy, Checks: []Check{}}
+		}
+		stale := *h.summary
+		stale.Stale = true
+		stale.Error = err.Error()
+		h.summary = &stale
+		return
+	}
+
+	s := h.evaluate(resp.Checks)
+	if resp.Reason != "" {
+		// The checks of the failed accounts are missing, so the policy
+		// can't be decided.
+		s.Status = "unknown"
+		s.Error = "partial results: " + resp.Reason
+	}
+	s.CheckedAt = time.Now()
+	h.summary = &s
+}
+
+// evaluate builds the summary of the included checks.
+func (h *Handler) evaluate(checks []observery.Check) Summary {
+	s := Summary{Policy: h.opts.Policy, Checks: []Check{}}
+
+	var (
+		listed = map[string]bool{}
+		found  = map[string]bool{}
+	)
+	for _, key := range h.opts.Checks {
+		listed[key] = true
+	}
+
+	for _, c := range checks {
+		include := listed[c.ID] || listed[c.Name]
+		if include {
+			found[c.ID], found[c.Name] = true, true
+		}
+		if len(h.selector) > 0 && h.selector.Match(c) {
+			include = true
+		}
+		if len(h.opts.Checks) == 0 && len(h.selector) == 0 {
+			include = true
+		}
+		if !include {
+			continue
+		}
+
+		s.Checks = append(s.Checks, Check{ID: c.ID, Name: c.Name, State: c.State, Active: c.Active, Since: c.Since})
+		if c.Active {
+			s.Total++
+			if c.State == "up" {
+				s.Up++
+			}
+		}
+	}
+
+	for _, key := range h.opts.Checks {
+		if !found[key] {
+			s.Checks = append(s.Checks, Check{Name: key, State: "missing", Active: true})
+			s.Total++
+		}
+	}
+
+	var up bool
+	switch h.opts.Policy {
+	case All:
+		up = s.Up == s.Total
+	case Any:
+		up = s.Up > 0
+	case Quorum:
+		quorum := h.opts.Quorum
+		if quorum == 0 {
+			quorum = s.Total/2 + 1
+		}
+		up = s.Up >= quorum
+	}
+
+	switch {
+	case s.Total == 0:
+		// Nothing to depend on is more likely a mistake than healthy.
+		s.Status = "unknown"
+		s.Error = "no active checks selected"
+	case up:
+		s.Status = "up"
+	default:
+		s.Status = "down"
+	}
+	return s
+}
@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type fakeLister struct {
+	checks []observery.Check
+	err    error
+}
+
+func (f *fakeLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &observery.ListChecksResponse{Success: true, Checks: f.checks}, nil
+}
+
+func TestPolicies(t *testing.T) {
+	lister := &fakeLister{checks: []observery.Check{
+		{ID: "1", Name: "db-a", Type: "ping", Active: true, State: "up"},
+		{ID: "2", Name: "db-b", Type: "ping", Active: true, State: "down"},
+		{ID: "3", Name: "db-c", Type: "ping", Active: true, State: "up"},
+		{ID: "4", Name: "db-d", Type: "ping", Active: false, State: "down"},
+		{ID: "5", Name: "web", Type: "http", Active: true, State: "up"},
+	}}
+
+	for _, test := range []struct {
+		opts   Options
+		status string
+		up     int
+		total  int
+	}{
+		{Options{Selector: "name=db-*"}, "down", 2, 3},
+		{Options{Selector: "name=db-*", Policy: Quorum}, "up", 2, 3},
+		{Options{Selector: "name=db-*", Policy: Quorum, Quorum: 3}, "down", 2, 3},
+		{Options{Checks: []string{"2", "web"}, Policy: Any}, "up", 1, 2},
+		{Options{Checks: []string{"web", "cache"}}, "down", 1, 2},
+		{Options{Checks: []string{"web"}, Selector: "id=1"}, "up", 2, 2},
+		{Options{Selector: "type=cert"}, "unknown", 0, 0},
+		{Options{Selector: "name=db-d", Policy: Quorum}, "unknown", 0, 0},
+	} {
+		h, err := NewHandler(lister, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := h.Summary(context.Background())
+		if s.Status != test.status || s.Up != test.up || s.Total != test.total {
+			t.Errorf("Expected %s %d/%d for %+v but got %s %d/%d\n", test.status, test.up, test.total, test.opts, s.Status, s.Up, s.Total)
+		}
+	}
+
+	if _, err := NewHandler(lister, Options{Policy: "most"}); err == nil {
+		t.Fatal("Expected an error for an unknown policy")
+	}
+}
+
+func TestNoChecks(t *testing.T) {
+	h, _ := NewHandler(&fakeLister{}, Options{Policy: Any})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dependencies", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without any checks but got %d\n", rec.Code)
+	}
+}
+
+func TestStale(t *testing.T) {
+	lister := &fakeLister{checks: []observery.Check{{ID: "1", Name: "db", Active: true, State: "up"}}}
+	h, _ := NewHandler(lister, Options{CacheTTL: -1})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dependencies", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %d\n", rec.Code)
+	}
+
+	lister.err = errors.New("connection refused")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dependencies", nil))
+
+	var s Summary
+	if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || !s.Stale || s.Error != "connection refused" || s.Status != "up" {
+		t.Fatalf("Expected the stale summary but got %d %+v\n", rec.Code, s)
+	}
+
+	h, _ = NewHandler(lister, Options{})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dependencies", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without any summary but got %d\n", rec.Code)
+	}
+}
+
+// slowLister blocks every call after the first until release is closed.
+type slowLister struct {
+	mu      sync.Mutex
+	calls   int
+	release chan struct{}
+}
+
+func (f *slowLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	first := f.calls == 1
+	f.mu.Unlock()
+
+	if !first {
+		<-f.release
+	}
+	return &observery.ListChecksResponse{Success: true, Checks: []observery.Check{{ID: "1", Name: "db", Active: true, State: "up"}}}, nil
+}
+
+func TestSlowRefresh(t *testing.T) {
+	lister := &slowLister{release: make(chan struct{})}
+	h, _ := NewHandler(lister, Options{CacheTTL: -1})
+	h.Summary(context.Background())
+
+	// The request starting the refresh waits for the hanging API.
+	refreshed := make(chan Summary)
+	go func() { refreshed <- h.Summary(context.Background()) }()
+	for i := 0; ; i++ {
+		lister.mu.Lock()
+		calls := lister.calls
+		lister.mu.Unlock()
+		if calls == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("The refresh never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Everyone else gets the previous summary without waiting or
+	// starting another refresh.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s := h.Summary(context.Background()); s.Status != "up" {
+				t.Errorf("Expected the cached summary but got %+v\n", s)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(lister.release)
+	if s := <-refreshed; s.Status != "up" || s.Stale {
+		t.Fatalf("Expected the refreshed summary but got %+v\n", s)
+	}
+	if lister.calls != 2 {
+		t.Fatalf("Expected a single refresh but the API was called %d times\n", lister.calls)
+	}
+}
+
+// brokenStaging fakes the observery API for a MultiClient, failing every
+// request made with the "staging" username.
+type brokenStaging struct{}
+
+func (brokenStaging) RoundTrip(req *http.Request) (*http.Response, error) {
+	if account, _, _ := req.BasicAuth(); account == "staging" {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"success": true, "result": [{"id": "1", "name": "db-a", "active": true, "state": "up"}]}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestPartialAccounts(t *testing.T) {
+	defer func(t http.RoundTripper) { http.DefaultTransport = t }(http.DefaultTransport)
+	http.DefaultTransport = brokenStaging{}
+
+	client, err := observery.NewMultiClient(map[string]*observery.Client{
+		"prod":    observery.NewClient("prod", "secret"),
+		"staging": observery.NewClient("staging", "secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, _ := NewHandler(client, Options{Selector: "name=db-*"})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/dependencies", nil))
+
+	var s Summary
+	if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || s.Status != "unknown" || !strings.Contains(s.Error, "staging") {
+		t.Fatalf("Expected an unknown status naming the failed account but got %d %+v\n", rec.Code, s)
+	}
+	if s.Up != 1 || s.Total != 1 {
+		t.Fatalf("Expected the checks of prod to be listed but got %+v\n", s)
+	}
+}
@@ -0,0 +1,41 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	if err := WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = Write(path, 0600, func(w io.Writer) error {
+		w.Write([]byte("half"))
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("Expected the write error to be returned")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil || string(data) != "old" {
+		t.Fatalf("Expected the old file to be kept but got %q: %v\n", data, err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("Expected the temporary file to be removed but got %d files\n", len(files))
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatalf("Unexpected file mode %v: %v\n", fi.Mode(), err)
+	}
+}
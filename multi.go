@@ -0,0 +1,296 @@
+package observery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountError is a failed request to one account of a MultiClient.
+type AccountError struct {
+	// Account is the name of the account.
+	Account string
+
+	// Err is what went wrong.
+	Err error
+}
+
+func (e *AccountError) Error() string {
+	return fmt.Sprintf("account %s: %s", e.Account, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError holds the accounts that failed during a MultiClient request.
+type MultiError []*AccountError
+
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "observery: " + strings.Join(msgs, "; ")
+}
+
+// MultiClient combines the clients of several accounts. Listing merges the
+// results of all accounts, fetched in parallel. Merged checks and outages
+// have their Account set and their ids qualified as "account/id", so they
+// stay unique across accounts and requests for a single check or outage
+// can be routed back to its account.
+type MultiClient struct {
+	clients map[string]*Client
+	names   []string
+}
+
+// NewMultiClient creates a MultiClient from clients keyed by account name.
+// Account names can't contain a slash.
+func NewMultiClient(clients map[string]*Client) (*MultiClient, error) {
+	m := &MultiClient{clients: map[string]*Client{}}
+	for name, c := range clients {
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("observery: invalid account name %q", name)
+		}
+		m.clients[name] = c
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	return m, nil
+}
+
+// NewMultiClientFromProfiles creates a MultiClient with an account for
+// each profile of the config file read by ProfileProvider. An empty path
+// uses the default location.
+func NewMultiClientFromProfiles(path string, profiles []string) (*MultiClient, error) {
+	clients := map[string]*Client{}
+	for _, profile := range profiles {
+		p := ProfileProvider{Path: path, Profile: profile}
+		if _, err := p.Credentials(); err != nil {
+			return nil, &AccountError{Account: profile, Err: err}
+		}
+		clients[profile] = NewClientWithProvider(p)
+	}
+	return NewMultiClient(clients)
+}
+
+// Accounts returns the account names in order.
+func (m *MultiClient) Accounts() []string {
+	return append([]string(nil), m.names...)
+}
+
+// Account returns the client of an account.
+func (m *MultiClient) Account(name string) (*Client, error) {
+	c, ok := m.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("observery: unknown account %q", name)
+	}
+	return c, nil
+}
+
+// SplitID splits an id qualified by a MultiClient into the account name
+// and the id within that account.
+func SplitID(id string) (account, accountID string, err error) {
+	i := strings.Index(id, "/")
+	if i < 1 {
+		return "", "", fmt.Errorf("observery: id %q isn't qualified with an account", id)
+	}
+	return id[:i], id[i+1:], nil
+}
+
+// route returns the client and unqualified id for a qualified id.
+func (m *MultiClient) route(id string) (*Client, string, error) {
+	account, id, err := SplitID(id)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := m.Account(account)
+	return c, id, err
+}
+
+// each calls f for every account in parallel and collects the errors in
+// account order.
+func (m *MultiClient) each(f func(account string, c *Client) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(m.names))
+	)
+	for i, name := range m.names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			errs[i] = f(name, m.clients[name])
+		}(i, name)
+	}
+	wg.Wait()
+
+	var failed MultiError
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, &AccountError{Account: m.names[i], Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// FetchChecks returns the checks of all accounts. If some accounts fail the
+// checks of the others are returned together with a MultiError.
+func (m *MultiClient) FetchChecks(ctx context.Context) ([]Check, error) {
+	results := make(map[string][]Check, len(m.names))
+	var mu sync.Mutex
+
+	err := m.each(func(account string, c *Client) error {
+		resp, err := c.ListChecks(ctx)
+		if err == nil && !resp.Success {
+			err = errors.New(resp.Reason)
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range resp.Checks {
+			resp.Checks[i].ID = account + "/" + resp.Checks[i].ID
+			resp.Checks[i].Account = account
+		}
+		mu.Lock()
+		results[account] = resp.Checks
+		mu.Unlock()
+		return nil
+	})
+
+	var checks []Check
+	for _, name := range m.names {
+		checks = append(checks, results[name]...)
+	}
+	return checks, err
+}
+
+// FetchOutages returns the outages of all accounts. If some accounts fail
+// the outages of the others are returned together with a MultiError.
+func (m *MultiClient) FetchOutages(ctx context.Context) ([]Outage, error) {
+	results := make(map[string][]Outage, len(m.names))
+	var mu sync.Mutex
+
+	err := m.each(func(account string, c *Client) error {
+		resp, err := c.ListOutages(ctx)
+		if err == nil && !resp.Success {
+			err = errors.New(resp.Reason)
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range resp.Outages {
+			o := &resp.Outages[i]
+			o.ID = account + "/" + o.ID
+			o.CheckID = account + "/" + o.CheckID
+			o.Account = account
+		}
+		mu.Lock()
+		results[account] = resp.Outages
+		mu.Unlock()
+		return nil
+	})
+
+	var outages []Outage
+	for _, name := range m.names {
+		outages = append(outages, results[name]...)
+	}
+	return outages, err
+}
+
+// ListChecks returns the merged checks of all accounts, so a MultiClient
+// can be used wherever a Client lists checks. It only fails if every
+// account fails. Partial failures are reported in Reason while Success
+// stays true.
+func (m *MultiClient) ListChecks(ctx context.Context) (*ListChecksResponse, error) {
+	checks, err := m.FetchChecks(ctx)
+	resp := &ListChecksResponse{Success: true, Checks: checks}
+	return resp, m.partial(err, &resp.Reason)
+}
+
+// ListOutages returns the merged outages of all accounts. Failures are
+// handled like in ListChecks.
+func (m *MultiClient) ListOutages(ctx context.Context) (*ListOutagesResponse, error) {
+	outages, err := m.FetchOutages(ctx)
+	resp := &ListOutagesResponse{Success: true, Outages: outages}
+	return resp, m.partial(err, &resp.Reason)
+}
+
+// partial returns err if every account failed and otherwise stores it in
+// reason.
+func (m *MultiClient) partial(err error, reason *string) error {
+	if failed, ok := err.(MultiError); ok && len(failed) < len(m.names) {
+		*reason = failed.Error()
+		return nil
+	}
+	return err
+}
+
+// GetCheck returns the check with the given qualified id from its account.
+// The id of the returned check is qualified too.
+func (m *MultiClient) GetCheck(ctx context.Context, id string) (*GetCheckResponse, error) {
+	c, accountID, err := m.route(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.GetCheck(ctx, accountID)
+	if err == nil && resp.Success {
+		resp.Check.ID = id
+	}
+	return resp, err
+}
+
+// CreateCheck creates a check in the given account.
+func (m *MultiClient) CreateCheck(ctx context.Context, account string, req *CreateCheckRequest) (*CreateCheckResponse, error) {
+	c, err := m.Account(account)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateCheck(ctx, req)
+}
+
+// UpdateCheck updates the check with the qualified id in req.ID in its
+// account. req isn't modified.
+func (m *MultiClient) UpdateCheck(ctx context.Context, req *UpdateCheckRequest) (*UpdateCheckResponse, error) {
+	c, id, err := m.route(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	r := *req
+	r.ID = id
+	return c.UpdateCheck(ctx, &r)
+}
+
+// DeleteCheck deletes the check with the given qualified id from its
+// account.
+func (m *MultiClient) DeleteCheck(ctx context.Context, id string) (*DeleteCheckResponse, error) {
+	c, id, err := m.route(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.DeleteCheck(ctx, id)
+}
+
+// GetOutage returns the outage with the given qualified id from its
+// account. The ids of the returned outage and its check are qualified too.
+func (m *MultiClient) GetOutage(ctx context.Context, id string) (*GetOutageResponse, error) {
+	c, accountID, err := m.route(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.GetOutage(ctx, accountID)
+	if err == nil && resp.Success {
+		account, _, _ := SplitID(id)
+		resp.Outage.ID = id
+		resp.Outage.CheckID = account + "/" + resp.Outage.CheckID
+	}
+	return resp, err
+}
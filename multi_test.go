@@ -0,0 +1,150 @@
+package observery
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI answers requests to the observery API with canned JSON bodies
+// keyed by method and path, and records the requests it received. When
+// sequence is set, every request is answered with its next body instead,
+// repeating the last one. A body of "error" fails the request.
+type fakeAPI struct {
+	bodies   map[string]string
+	sequence []string
+	fail     bool
+
+	// after is called with the number of requests so far once a request
+	// has been answered.
+	after func(n int)
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/api/v1")
+	f.mu.Lock()
+	f.requests = append(f.requests, key)
+	n := len(f.requests)
+	f.mu.Unlock()
+	if f.after != nil {
+		defer f.after(n)
+	}
+
+	body, ok := f.bodies[key]
+	if len(f.sequence) > 0 {
+		body, ok = f.sequence[len(f.sequence)-1], true
+		if n <= len(f.sequence) {
+			body = f.sequence[n-1]
+		}
+	}
+	if f.fail || body == "error" {
+		return nil, errors.New("connection refused")
+	}
+	if !ok {
+		body = `{"success": false, "reason": "not found"}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func fakeClient(api *fakeAPI) *Client {
+	c := NewClient("user", "pass")
+	c.client = &http.Client{Transport: api}
+	return c
+}
+
+func TestMultiClient(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		prod = &fakeAPI{bodies: map[string]string{
+			"GET /check":   `{"success": true, "result": [{"id": "1", "name": "api", "type": "http", "state": "up"}]}`,
+			"GET /check/1": `{"success": true, "result": {"id": "1", "name": "api", "type": "http", "state": "up"}}`,
+			"PUT /check/1": `{"success": true, "result": {"id": "1", "message": "updated"}}`,
+			"GET /outage":  `{"success": true, "result": [{"id": "9", "checkId": "1", "checkName": "api", "start": "2020-01-02T03:04:05", "duration": 1000}]}`,
+		}}
+		staging = &fakeAPI{bodies: map[string]string{
+			"GET /check":  `{"success": true, "result": [{"id": "1", "name": "api", "type": "http", "state": "down"}]}`,
+			"GET /outage": `{"success": true, "result": []}`,
+		}}
+		broken = &fakeAPI{fail: true}
+	)
+
+	if _, err := NewMultiClient(map[string]*Client{"a/b": fakeClient(prod)}); err == nil {
+		t.Fatal("Expected account names with a slash to be rejected")
+	}
+
+	m, err := NewMultiClient(map[string]*Client{
+		"prod":    fakeClient(prod),
+		"staging": fakeClient(staging),
+		"broken":  fakeClient(broken),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks, err := m.ListChecks(ctx)
+	if err != nil {
+		t.Fatalf("Expected partial results but got %s\n", err)
+	}
+	if !checks.Success || !strings.Contains(checks.Reason, "account broken") {
+		t.Fatalf("Expected the broken account in Reason but got %q\n", checks.Reason)
+	}
+	if len(checks.Checks) != 2 || checks.Checks[0].ID != "prod/1" || checks.Checks[0].Account != "prod" || checks.Checks[1].ID != "staging/1" {
+		t.Fatalf("Unexpected merged checks %+v\n", checks.Checks)
+	}
+
+	outages, err := m.ListOutages(ctx)
+	if err != nil || len(outages.Outages) != 1 {
+		t.Fatalf("Unexpected merged outages %+v: %v\n", outages, err)
+	}
+	if o := outages.Outages[0]; o.ID != "prod/9" || o.CheckID != "prod/1" || o.Account != "prod" {
+		t.Fatalf("Expected qualified outage ids but got %+v\n", o)
+	}
+
+	check, err := m.GetCheck(ctx, "prod/1")
+	if err != nil || check.Check.ID != "prod/1" {
+		t.Fatalf("Unexpected check %+v: %v\n", check, err)
+	}
+
+	req := &UpdateCheckRequest{ID: "prod/1"}
+	if _, err := m.UpdateCheck(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ID != "prod/1" {
+		t.Fatalf("Expected the request to be left alone but its id is %q\n", req.ID)
+	}
+	if last := prod.requests[len(prod.requests)-1]; last != "PUT /check/1" {
+		t.Fatalf("Expected the update to be routed to prod but got %q\n", last)
+	}
+	for _, r := range staging.requests {
+		if strings.HasPrefix(r, "PUT") {
+			t.Fatalf("The update was sent to staging: %q\n", r)
+		}
+	}
+
+	if _, err := m.GetCheck(ctx, "1"); err == nil {
+		t.Fatal("Expected an unqualified id to fail")
+	}
+	if _, err := m.GetCheck(ctx, "dev/1"); err == nil {
+		t.Fatal("Expected an unknown account to fail")
+	}
+
+	// Every account failing is an error.
+	all, _ := NewMultiClient(map[string]*Client{"broken": fakeClient(broken)})
+	_, err = all.ListChecks(ctx)
+	var failed MultiError
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].Account != "broken" {
+		t.Fatalf("Expected a MultiError for the broken account but got %v\n", err)
+	}
+}
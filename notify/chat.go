@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Slack posts to a Slack incoming webhook.
+type Slack struct {
+	// URL of the incoming webhook.
+	URL string
+
+	Options
+}
+
+// Notify posts the rendered message to Slack.
+func (s *Slack) Notify(ctx context.Context, hook *observery.Webhook) error {
+	return s.postJSON(ctx, s.URL, defaultTemplate, hook, func(msg string) interface{} {
+		return map[string]string{"text": msg}
+	})
+}
+
+// Teams posts a message card to a Microsoft Teams connector.
+type Teams struct {
+	// URL of the incoming webhook connector.
+	URL string
+
+	Options
+}
+
+// Notify posts the rendered message to Teams. The card is red for down
+// and green for up webhooks.
+func (t *Teams) Notify(ctx context.Context, hook *observery.Webhook) error {
+	return t.postJSON(ctx, t.URL, defaultTemplate, hook, func(msg string) interface{} {
+		return map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    hook.CheckName + " is " + hook.State,
+			"themeColor": stateColor(hook.State),
+			"text":       msg,
+		}
+	})
+}
+
+// Discord posts to a Discord webhook.
+type Discord struct {
+	// URL of the Discord webhook.
+	URL string
+
+	Options
+}
+
+// Notify posts the rendered message to Discord.
+func (d *Discord) Notify(ctx context.Context, hook *observery.Webhook) error {
+	return d.postJSON(ctx, d.URL, defaultTemplate, hook, func(msg string) interface{} {
+		return map[string]string{"content": msg}
+	})
+}
+
+func stateColor(state string) string {
+	switch state {
+	case "up":
+		return "2E9D4F"
+	case "down":
+		return "C9302C"
+	}
+	return "E6A23C"
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+
+	"github.com/sfreiberg/observery"
+)
+
+// genericTemplate is the body sent by Generic when no template is set.
+var genericTemplate = template.Must(ParseTemplate(`{"checkId":{{json .CheckID}},"checkName":{{json .CheckName}},` +
+	`"checkType":{{json .CheckType}},"state":{{json .State}},"httpStatusCode":{{.HTTPStatusCode}},` +
+	`"responseTime":{{.ResponseTime.Milliseconds}},"timedOut":{{.TimedOut}},"details":{{json .Details}}}`))
+
+// Generic posts a templated JSON body to any URL. The template must
+// produce the complete body; use the json function to quote values.
+type Generic struct {
+	// URL the body is posted to.
+	URL string
+
+	// Header holds additional request headers, such as Authorization.
+	Header http.Header
+
+	Options
+}
+
+// Notify renders the body and posts it.
+func (g *Generic) Notify(ctx context.Context, hook *observery.Webhook) error {
+	body, err := g.render(ctx, genericTemplate, hook)
+	if err != nil {
+		return err
+	}
+	return g.post(ctx, g.URL, []byte(body), g.Header)
+}
@@ -0,0 +1,249 @@
+// Package notify sends observery webhooks to chat tools and other HTTP
+// endpoints. Every notifier renders its message from a Go text/template
+// whose data is a Data value, and retries failed deliveries.
+//
+// All notifiers satisfy routing.Notifier, so they can be used as receivers
+// in a routing tree.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// DefaultTemplate is the message template used when Options.Template is
+// nil.
+const DefaultTemplate = `{{.CheckName}} is {{.State}}` +
+	`{{if .HTTPStatusCode}} (HTTP {{.HTTPStatusCode}}){{end}}` +
+	`{{if .TimedOut}}, timed out{{end}}` +
+	`{{with .Check}}{{if .URL}} {{.URL}}{{else if .Host}} {{.Host}}{{end}}{{end}}` +
+	`{{if .Details}}: {{.Details}}{{end}}`
+
+// Notifier sends a notification about a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, hook *observery.Webhook) error
+}
+
+// Data is passed to message templates. The webhook fields can be used
+// directly, as in {{.CheckName}}.
+type Data struct {
+	*observery.Webhook
+
+	// Check holds the details of the check when Options.Lookup is set and
+	// found it. Nil otherwise.
+	Check *observery.Check
+
+	// LookupError explains why Options.Lookup failed, for example because
+	// the account of the check couldn't be listed.
+	LookupError string
+}
+
+// LookupFunc returns the details of a check for use in templates.
+type LookupFunc func(ctx context.Context, checkID string) (*observery.Check, error)
+
+// Lister is the part of observery.Client used by ListLookup.
+type Lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+}
+
+// lookupTTL is how long ListLookup reuses a list of checks.
+const lookupTTL = time.Minute
+
+// ListLookup returns a LookupFunc that finds checks with Client.ListChecks.
+// The list is reused for a minute, so an outage that hits many checks
+// doesn't list them all once per notification.
+func ListLookup(client Lister) LookupFunc {
+	var (
+		mu      sync.Mutex
+		resp    *observery.ListChecksResponse
+		fetched time.Time
+	)
+	return func(ctx context.Context, checkID string) (*observery.Check, error) {
+		// Holding mu while listing makes concurrent lookups share a
+		// single request.
+		mu.Lock()
+		if resp == nil || time.Since(fetched) >= lookupTTL {
+			r, err := client.ListChecks(ctx)
+			if err == nil && !r.Success {
+				err = errors.New(r.Reason)
+			}
+			if err != nil {
+				mu.Unlock()
+				return nil, err
+			}
+			resp, fetched = r, time.Now()
+		}
+		checks, reason := resp.Checks, resp.Reason
+		mu.Unlock()
+
+		for _, c := range checks {
+			if c.ID == checkID {
+				return &c, nil
+			}
+		}
+		if reason != "" {
+			// The check may belong to an account that couldn't be listed.
+			return nil, errors.New(reason)
+		}
+		return nil, nil
+	}
+}
+
+// Options are the settings shared by every notifier.
+type Options struct {
+	// Template is the message template, usually created with
+	// ParseTemplate. Defaults to DefaultTemplate, or for Generic to the
+	// webhook as JSON.
+	Template *template.Template
+
+	// Lookup, when set, is used to add check details to the template data.
+	Lookup LookupFunc
+
+	// Timeout limits each delivery attempt. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// Retries is the number of times a failed delivery is retried.
+	// Network errors, 429 and 5xx responses are retried.
+	Retries int
+
+	// RetryDelay is the delay before the first retry. It doubles with
+	// every retry. Defaults to one second.
+	RetryDelay time.Duration
+
+	// Client is used to send requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+var defaultTemplate = template.Must(ParseTemplate(DefaultTemplate))
+
+// ParseTemplate parses a message template for Options.Template. Parse it
+// when loading the configuration, so a malformed template is reported
+// then rather than when the first alert is sent. The json function quotes
+// a value as JSON.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("notify").Funcs(funcs).Parse(text)
+}
+
+// render executes the template, or fallback if none is configured.
+func (o *Options) render(ctx context.Context, fallback *template.Template, hook *observery.Webhook) (string, error) {
+	tmpl := o.Template
+	if tmpl == nil {
+		tmpl = fallback
+	}
+
+	data := Data{Webhook: hook}
+	if o.Lookup != nil {
+		// Missing details only make the message less detailed.
+		var err error
+		if data.Check, err = o.Lookup(ctx, hook.CheckID); err != nil {
+			data.LookupError = err.Error()
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// post sends body to url, retrying as configured.
+func (o *Options) post(ctx context.Context, url string, body []byte, header http.Header) error {
+	var (
+		delay = o.RetryDelay
+		err   error
+	)
+	if delay == 0 {
+		delay = time.Second
+	}
+
+	for attempt := 0; attempt <= o.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return err
+			}
+			delay *= 2
+		}
+
+		var retry bool
+		if retry, err = o.send(ctx, url, body, header); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// send makes a single delivery attempt. The bool reports whether a failure
+// is worth retrying.
+func (o *Options) send(ctx context.Context, url string, body []byte, header http.Header) (bool, error) {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("notify: %s returned %s: %s", req.URL.Host, resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// postJSON renders the message with fallback, wraps it with payload and
+// posts the result.
+func (o *Options) postJSON(ctx context.Context, url string, fallback *template.Template, hook *observery.Webhook, payload func(msg string) interface{}) error {
+	msg, err := o.render(ctx, fallback, hook)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload(msg))
+	if err != nil {
+		return err
+	}
+	return o.post(ctx, url, body, nil)
+}
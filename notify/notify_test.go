@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/routing"
+)
+
+var hook = &observery.Webhook{
+	CheckID:        "1",
+	CheckName:      "api",
+	CheckType:      "http",
+	State:          "down",
+	HTTPStatusCode: 502,
+	ResponseTime:   1500 * time.Millisecond,
+	Details:        `Bad "gateway"`,
+}
+
+// recorder serves failures first and then records the request body.
+func recorder(failures int) (*httptest.Server, *[]byte) {
+	var body []byte
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+	})), &body
+}
+
+func TestSlack(t *testing.T) {
+	ts, body := recorder(2)
+	defer ts.Close()
+
+	lookup := func(ctx context.Context, id string) (*observery.Check, error) {
+		return &observery.Check{ID: id, URL: "https://example.com"}, nil
+	}
+	var n routing.Notifier = &Slack{URL: ts.URL, Options: Options{Retries: 2, RetryDelay: time.Millisecond, Lookup: lookup}}
+	if err := n.Notify(context.Background(), hook); err != nil {
+		t.Fatalf("Error notifying: %s\n", err)
+	}
+
+	msg := map[string]string{}
+	json.Unmarshal(*body, &msg)
+	if want := `api is down (HTTP 502) https://example.com: Bad "gateway"`; msg["text"] != want {
+		t.Fatalf("Expected %q but got %q\n", want, msg["text"])
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	ts, _ := recorder(3)
+	defer ts.Close()
+
+	d := &Discord{URL: ts.URL, Options: Options{Retries: 1, RetryDelay: time.Millisecond}}
+	if err := d.Notify(context.Background(), hook); err == nil {
+		t.Fatal("Expected an error after all retries failed")
+	}
+}
+
+func TestTeams(t *testing.T) {
+	ts, body := recorder(0)
+	defer ts.Close()
+
+	tm := &Teams{URL: ts.URL, Options: Options{Template: template.Must(ParseTemplate("{{.CheckName}} {{.State}}"))}}
+	if err := tm.Notify(context.Background(), hook); err != nil {
+		t.Fatalf("Error notifying: %s\n", err)
+	}
+
+	card := map[string]string{}
+	json.Unmarshal(*body, &card)
+	if card["@type"] != "MessageCard" || card["text"] != "api down" || card["themeColor"] != "C9302C" {
+		t.Fatalf("Unexpected card: %v\n", card)
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	ts, body := recorder(0)
+	defer ts.Close()
+
+	g := &Generic{URL: ts.URL}
+	if err := g.Notify(context.Background(), hook); err != nil {
+		t.Fatalf("Error notifying: %s\n", err)
+	}
+
+	got := struct {
+		CheckID      string `json:"checkId"`
+		ResponseTime int    `json:"responseTime"`
+		Details      string `json:"details"`
+	}{}
+	if err := json.Unmarshal(*body, &got); err != nil {
+		t.Fatalf("Expected valid JSON but got %s: %s\n", *body, err)
+	}
+	if got.CheckID != "1" || got.ResponseTime != 1500 || got.Details != hook.Details {
+		t.Fatalf("Unexpected body: %s\n", *body)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	if _, err := ParseTemplate("{{.CheckName"); err == nil {
+		t.Fatal("Expected a malformed template to fail when parsed")
+	}
+}
+
+type countingLister struct {
+	calls int
+}
+
+func (l *countingLister) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	l.calls++
+	return &observery.ListChecksResponse{Success: true, Checks: []observery.Check{{ID: "1", Name: "api"}}}, nil
+}
+
+func TestListLookupCache(t *testing.T) {
+	var (
+		client = &countingLister{}
+		lookup = ListLookup(client)
+	)
+	for i := 0; i < 10; i++ {
+		if c, err := lookup(context.Background(), "1"); err != nil || c == nil || c.Name != "api" {
+			t.Fatalf("Expected the check but got %+v: %v\n", c, err)
+		}
+	}
+	if client.calls != 1 {
+		t.Fatalf("Expected the checks to be listed once but got %d calls\n", client.calls)
+	}
+}
+
+// brokenStaging fakes the observery API for a MultiClient, failing every
+// request made with the "staging" username.
+type brokenStaging struct{}
+
+func (brokenStaging) RoundTrip(req *http.Request) (*http.Response, error) {
+	if account, _, _ := req.BasicAuth(); account == "staging" {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"success": true, "result": [{"id": "1", "name": "api", "url": "https://example.com"}]}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestListLookupPartial(t *testing.T) {
+	defer func(t http.RoundTripper) { http.DefaultTransport = t }(http.DefaultTransport)
+	http.DefaultTransport = brokenStaging{}
+
+	client, err := observery.NewMultiClient(map[string]*observery.Client{
+		"prod":    observery.NewClient("prod", "secret"),
+		"staging": observery.NewClient("staging", "secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := ListLookup(client)
+	if c, err := lookup(context.Background(), "prod/1"); err != nil || c == nil || c.Name != "api" {
+		t.Fatalf("Expected the check of prod but got %+v: %v\n", c, err)
+	}
+
+	tmpl, _ := ParseTemplate("{{.CheckName}}{{with .Check}} {{.URL}}{{end}}{{with .LookupError}} ({{.}}){{end}}")
+	o := &Options{Template: tmpl, Lookup: lookup}
+	msg, err := o.render(context.Background(), defaultTemplate, &observery.Webhook{CheckID: "staging/1", CheckName: "db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(msg, "db (") || !strings.Contains(msg, "staging") {
+		t.Fatalf("Expected the failed account in the message but got %q\n", msg)
+	}
+}
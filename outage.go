@@ -15,29 +15,36 @@ type ListOutagesResponse struct {
 	Reason string
 
 	// Outage is a slice of outages.
-	Outages []struct {
-		// ID of the outage.
-		ID string
+	Outages []Outage
+}
 
-		// CheckID of the check that the outage belongs to.
-		CheckID string
+// Outage holds the summary of an outage as returned by Client.ListOutages.
+type Outage struct {
+	// ID of the outage.
+	ID string
 
-		// CheckName is the friendly name of the check that the outage belongs
-		// to.
-		CheckName string
+	// CheckID of the check that the outage belongs to.
+	CheckID string
 
-		// Ongoing returns true it the outage is ongoing.
-		Ongoing bool
+	// CheckName is the friendly name of the check that the outage belongs
+	// to.
+	CheckName string
 
-		// Start of when the outage began.
-		Start time.Time
+	// Ongoing returns true it the outage is ongoing.
+	Ongoing bool
 
-		// Stop is the date/time when the outage concluded.
-		Stop time.Time
+	// Start of when the outage began.
+	Start time.Time
 
-		// Duration of the outage.
-		Duration time.Duration
-	}
+	// Stop is the date/time when the outage concluded.
+	Stop time.Time
+
+	// Duration of the outage.
+	Duration time.Duration
+
+	// Account is the name of the account the outage belongs to when it
+	// was listed through a MultiClient. Empty otherwise.
+	Account string `json:",omitempty"`
 }
 
 // GetOutageResponse contains the server response when requesting an individual
@@ -84,20 +91,6 @@ type GetOutageResponse struct {
 
 // ListOutages returns the 100 most recent outages.
 func (c *Client) ListOutages(ctx context.Context) (*ListOutagesResponse, error) {
-	// This is used internally because the response contains an anonymous
-	// struct. I could have pulled this but getting all outages has different
-	// fields than getting a single outage. So more code here but better
-	// and more obvious user experience.
-	type Outage struct {
-		ID        string
-		CheckID   string
-		CheckName string
-		Ongoing   bool
-		Start     time.Time
-		Stop      time.Time
-		Duration  time.Duration
-	}
-
 	s := &struct {
 		Success bool   `json:"success"`
 		Reason  string `json:"reason"`
@@ -182,7 +175,7 @@ func (c *Client) GetOutage(ctx context.Context, id string) (*GetOutageResponse,
 	resp.Outage.CheckName = s.Outage.CheckName
 	resp.Outage.Ongoing = s.Outage.Ongoing
 	resp.Outage.Duration = time.Duration(s.Outage.Duration) * time.Millisecond
-	resp.Outage.ResponseTime = time.Duration(s.Outage.Duration) * time.Millisecond
+	resp.Outage.ResponseTime = time.Duration(s.Outage.ResponseTime) * time.Millisecond
 	resp.Outage.Details = s.Outage.Details
 
 	start, err := time.Parse("2006-01-02T15:04:05", s.Outage.Start)
@@ -0,0 +1,63 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// probeCert connects to req.Host and checks that its certificate is valid
+// for more than req.CertExpirationDays days. It returns the days remaining
+// whenever the certificate could be read, even if it expires too soon.
+func (p *Prober) probeCert(ctx context.Context, req *observery.CreateCheckRequest, result *observery.Webhook) (*int, error) {
+	if req.CertExpirationDays == nil {
+		return nil, errors.New("certExpirationDays is required")
+	}
+
+	days, err := p.CertDaysRemaining(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if days <= *req.CertExpirationDays {
+		return &days, fmt.Errorf("certificate expires in %d days", days)
+	}
+	result.Details = fmt.Sprintf("certificate expires in %d days", days)
+	return &days, nil
+}
+
+// CertDaysRemaining connects to req.Host, port 443 unless req.Port is set,
+// and returns the number of whole days until its certificate expires. The
+// certificate chain must verify.
+func (p *Prober) CertDaysRemaining(ctx context.Context, req *observery.CreateCheckRequest) (int, error) {
+	addr, err := address(req, 443)
+	if err != nil {
+		return 0, err
+	}
+
+	d := &net.Dialer{}
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer raw.Close()
+
+	conn := tls.Client(raw, p.tlsConfig(*req.Host))
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := conn.Handshake(); err != nil {
+		return 0, err
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return 0, errors.New("no certificate presented")
+	}
+	return int(time.Until(certs[0].NotAfter).Hours() / 24), nil
+}
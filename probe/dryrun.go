@@ -0,0 +1,70 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/sfreiberg/observery"
+)
+
+// TestResult is the outcome of Prober.TestCheck.
+type TestResult struct {
+	// Valid is false when the request failed client side validation. No
+	// lookups or probes are done in that case.
+	Valid bool
+
+	// Err holds the validation errors, or the DNS error if the host
+	// couldn't be resolved.
+	Err error
+
+	// Addresses are the resolved addresses of the host or url.
+	Addresses []string
+
+	// Result is the outcome of running the check once. Nil if the check
+	// wasn't run.
+	Result *observery.Webhook
+
+	// CertDaysRemaining is the number of days until the certificate
+	// expires. Only set for cert checks.
+	CertDaysRemaining *int
+}
+
+// Passed returns true if the check is valid and would currently be up.
+func (r *TestResult) Passed() bool {
+	return r.Valid && r.Err == nil && r.Result != nil && r.Result.State == "up"
+}
+
+// TestCheck runs TestCheck using a Prober with the default settings.
+func TestCheck(ctx context.Context, req *observery.CreateCheckRequest) *TestResult {
+	return defaultProber.TestCheck(ctx, req)
+}
+
+// TestCheck is a dry run of creating req. It validates the request,
+// resolves the host and runs the check once locally. Nothing is created on
+// observery.com.
+func (p *Prober) TestCheck(ctx context.Context, req *observery.CreateCheckRequest) *TestResult {
+	if err := req.Validate(); err != nil {
+		return &TestResult{Err: err}
+	}
+	result := &TestResult{Valid: true}
+
+	host := ""
+	if req.Type == "http" {
+		if u, err := url.Parse(*req.URL); err == nil {
+			host = u.Hostname()
+		}
+	} else {
+		host = *req.Host
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Addresses = addrs
+
+	result.Result, result.CertDaysRemaining = p.probe(ctx, req)
+	return result
+}
@@ -0,0 +1,76 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sfreiberg/observery"
+)
+
+// probeHTTP requests req.URL and treats any 2xx or 3xx status as up. A POST
+// is sent when req.SendData is set.
+func (p *Prober) probeHTTP(ctx context.Context, req *observery.CreateCheckRequest, result *observery.Webhook) error {
+	if req.URL == nil || *req.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+
+	var (
+		method = "GET"
+		body   io.Reader
+	)
+	if req.SendData != nil && *req.SendData != "" {
+		method = "POST"
+		body = strings.NewReader(*req.SendData)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, *req.URL, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if req.HTTPHeaders != nil {
+		for _, line := range strings.Split(*req.HTTPHeaders, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			i := strings.Index(line, ":")
+			if i < 1 {
+				return fmt.Errorf("invalid http header %q", line)
+			}
+			r.Header.Add(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+		}
+	}
+
+	if req.Username != nil || req.Password != nil {
+		var username, password string
+		if req.Username != nil {
+			username = *req.Username
+		}
+		if req.Password != nil {
+			password = *req.Password
+		}
+		r.SetBasicAuth(username, password)
+	}
+
+	client := &http.Client{Transport: p.httpTransport()}
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	result.HTTPStatusCode = resp.StatusCode
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Package probe runs observery checks locally. It can be used to find out
+// whether a check would pass before creating it, or as a fallback when
+// observery.com can't be reached.
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Prober runs checks locally. The zero value is ready to use. A Prober
+// keeps idle http connections between checks, so reuse it instead of
+// creating one per check.
+type Prober struct {
+	// Timeout limits how long a single check may take. Defaults to 30
+	// seconds.
+	Timeout time.Duration
+
+	// TLSConfig is used for http, cert and secure checks. ServerName is
+	// filled in from the check when empty. It must not be changed after the
+	// first check.
+	TLSConfig *tls.Config
+
+	once      sync.Once
+	transport *http.Transport
+}
+
+// defaultProber is used by Probe and TestCheck.
+var defaultProber = &Prober{}
+
+// Probe runs req using a Prober with the default settings.
+func Probe(ctx context.Context, req *observery.CreateCheckRequest) *observery.Webhook {
+	return defaultProber.Probe(ctx, req)
+}
+
+// Probe runs req once and returns the result in the same shape observery
+// uses for its webhooks. State is "up" or "down". Details explains why a
+// check is down or, for cert checks, how long the certificate is valid.
+func (p *Prober) Probe(ctx context.Context, req *observery.CreateCheckRequest) *observery.Webhook {
+	result, _ := p.probe(ctx, req)
+	return result
+}
+
+// probe runs req once. For cert checks it also returns the number of days
+// until the certificate expires, if it could be read.
+func (p *Prober) probe(ctx context.Context, req *observery.CreateCheckRequest) (*observery.Webhook, *int) {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &observery.Webhook{
+		CheckName: req.Name,
+		CheckType: req.Type,
+		State:     "up",
+	}
+
+	var (
+		start    = time.Now()
+		certDays *int
+		err      error
+	)
+	switch req.Type {
+	case "http":
+		err = p.probeHTTP(ctx, req, result)
+	case "ping":
+		err = p.probePing(ctx, req)
+	case "ssh", "ftp", "pop", "smtp", "imap":
+		err = p.probeBanner(ctx, req)
+	case "cert":
+		certDays, err = p.probeCert(ctx, req, result)
+	default:
+		err = fmt.Errorf("unknown check type %q", req.Type)
+	}
+	result.ResponseTime = time.Since(start)
+
+	if err != nil {
+		result.State = "down"
+		result.Details = err.Error()
+		result.TimedOut = isTimeout(ctx, err)
+	}
+
+	return result, certDays
+}
+
+// tlsConfig returns a copy of p.TLSConfig for host.
+func (p *Prober) tlsConfig(host string) *tls.Config {
+	config := &tls.Config{}
+	if p.TLSConfig != nil {
+		config = p.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// httpTransport returns the transport shared by the http checks of p.
+// ServerName is left to net/http, which sets it for every connection so
+// redirects to other hosts verify against the right name.
+func (p *Prober) httpTransport() *http.Transport {
+	p.once.Do(func() {
+		config := &tls.Config{}
+		if p.TLSConfig != nil {
+			config = p.TLSConfig.Clone()
+		}
+		p.transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: config,
+		}
+	})
+	return p.transport
+}
+
+// address returns host:port for req using port when req.Port isn't set.
+func address(req *observery.CreateCheckRequest, port int) (string, error) {
+	if req.Host == nil || *req.Host == "" {
+		return "", errors.New("host is required")
+	}
+	if req.Port != nil {
+		port = *req.Port
+	}
+	return net.JoinHostPort(*req.Host, strconv.Itoa(port)), nil
+}
+
+func isTimeout(ctx context.Context, err error) bool {
+	if ctx.Err() == context.DeadlineExceeded {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,328 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// serveText accepts a single connection and writes the greeting.
+func serveText(t *testing.T, greeting string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s\n", err)
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte(greeting))
+		ioutil.ReadAll(conn)
+	}()
+
+	return l
+}
+
+func TestHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username != "user" || password != "pass" || r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == "POST" && r.FormValue("a") != "b" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	req := &observery.CreateCheckRequest{
+		Type:        "http",
+		Name:        "web",
+		URL:         observery.PtrString(ts.URL),
+		Username:    observery.PtrString("user"),
+		Password:    observery.PtrString("pass"),
+		SendData:    observery.PtrString("a=b"),
+		HTTPHeaders: observery.PtrString("X-Test: yes\n"),
+	}
+	result := Probe(context.Background(), req)
+	if result.State != "up" || result.HTTPStatusCode != 200 {
+		t.Fatalf("Expected the check to be up but got %+v\n", result)
+	}
+	if result.CheckName != "web" || result.CheckType != "http" {
+		t.Fatalf("Expected the check name and type to be set but got %+v\n", result)
+	}
+
+	req.Password = observery.PtrString("wrong")
+	if result := Probe(context.Background(), req); result.State != "down" || result.HTTPStatusCode != 401 {
+		t.Fatalf("Expected the check to be down but got %+v\n", result)
+	}
+}
+
+// localhostCert returns a self-signed certificate that is only valid for
+// the name localhost.
+func localhostCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestHTTPRedirect(t *testing.T) {
+	cert := localhostCert(t)
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	target.StartTLS()
+	defer target.Close()
+	u, _ := url.Parse(target.URL)
+
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.RedirectHandler("https://localhost:"+u.Port()+"/", http.StatusFound))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	pool.AddCert(leaf)
+	p := &Prober{TLSConfig: &tls.Config{RootCAs: pool}}
+
+	req := &observery.CreateCheckRequest{Type: "http", URL: observery.PtrString(ts.URL)}
+	for i := 0; i < 2; i++ {
+		if result := p.Probe(context.Background(), req); result.State != "up" {
+			t.Fatalf("Expected the redirect to another host to be verified but got %+v\n", result)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("Expected the connection to be reused but got %d connections\n", n)
+	}
+}
+
+func TestBanner(t *testing.T) {
+	l := serveText(t, "SSH-2.0-OpenSSH_8.0\r\n")
+	defer l.Close()
+	addr := l.Addr().(*net.TCPAddr)
+	req := &observery.CreateCheckRequest{
+		Type: "ssh",
+		Host: observery.PtrString(addr.IP.String()),
+		Port: observery.PtrInt(addr.Port),
+	}
+	if result := Probe(context.Background(), req); result.State != "up" {
+		t.Fatalf("Expected ssh to be up but got %+v\n", result)
+	}
+
+	l = serveText(t, "220-mail.example.com\r\n220 ESMTP\r\n")
+	defer l.Close()
+	addr = l.Addr().(*net.TCPAddr)
+	req = &observery.CreateCheckRequest{
+		Type: "smtp",
+		Host: observery.PtrString(addr.IP.String()),
+		Port: observery.PtrInt(addr.Port),
+	}
+	if result := Probe(context.Background(), req); result.State != "up" {
+		t.Fatalf("Expected smtp to be up but got %+v\n", result)
+	}
+
+	l = serveText(t, "-ERR go away\r\n")
+	defer l.Close()
+	addr = l.Addr().(*net.TCPAddr)
+	req = &observery.CreateCheckRequest{
+		Type: "pop",
+		Host: observery.PtrString(addr.IP.String()),
+		Port: observery.PtrInt(addr.Port),
+	}
+	if result := Probe(context.Background(), req); result.State != "down" {
+		t.Fatalf("Expected pop to be down but got %+v\n", result)
+	}
+}
+
+func TestStartTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s\n", err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("* OK IMAP ready\r\n"))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line != "a1 STARTTLS\r\n" {
+			return
+		}
+		conn.Write([]byte("a1 OK begin TLS\r\n"))
+		tls.Server(conn, ts.TLS).Handshake()
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	p := &Prober{TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"}}
+
+	port := l.Addr().(*net.TCPAddr).Port
+	req := &observery.CreateCheckRequest{
+		Type:   "imap",
+		Host:   observery.PtrString("127.0.0.1"),
+		Port:   observery.PtrInt(port),
+		Secure: observery.PtrBool(true),
+	}
+	if result := p.Probe(context.Background(), req); result.State != "up" {
+		t.Fatalf("Expected imap to be up but got %+v\n", result)
+	}
+}
+
+func TestCert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	p := &Prober{TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"}}
+
+	req := &observery.CreateCheckRequest{
+		Type:               "cert",
+		Host:               observery.PtrString(u.Hostname()),
+		Port:               observery.PtrInt(port),
+		CertExpirationDays: observery.PtrInt(14),
+	}
+	if result := p.Probe(context.Background(), req); result.State != "up" {
+		t.Fatalf("Expected cert to be up but got %+v\n", result)
+	}
+
+	req.CertExpirationDays = observery.PtrInt(1000000)
+	if result := p.Probe(context.Background(), req); result.State != "down" {
+		t.Fatalf("Expected cert to be down but got %+v\n", result)
+	}
+
+	if result := Probe(context.Background(), req); result.State != "down" {
+		t.Fatalf("Expected an untrusted cert to be down but got %+v\n", result)
+	}
+}
+
+func TestPing(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %s\n", err)
+	}
+	defer l.Close()
+
+	req := &observery.CreateCheckRequest{
+		Type: "ping",
+		Host: observery.PtrString("127.0.0.1"),
+		Port: observery.PtrInt(l.Addr().(*net.TCPAddr).Port),
+	}
+	if result := Probe(context.Background(), req); result.State != "up" {
+		t.Fatalf("Expected ping to be up but got %+v\n", result)
+	}
+}
+
+func TestTestCheck(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	req := &observery.CreateCheckRequest{Type: "http", Interval: 5, URL: observery.PtrString("ftp://example.com")}
+	result := TestCheck(context.Background(), req)
+	if result.Valid || result.Passed() {
+		t.Fatalf("Expected the check to be invalid but got %+v\n", result)
+	}
+	if errs, ok := result.Err.(observery.ValidationErrors); !ok || len(errs) != 2 {
+		t.Fatalf("Expected errors for name and url but got %v\n", result.Err)
+	}
+
+	req.Name = "web"
+	req.URL = observery.PtrString(ts.URL)
+	result = TestCheck(context.Background(), req)
+	if !result.Valid || result.Passed() || result.Result.HTTPStatusCode != 404 {
+		t.Fatalf("Expected the check to be down but got %+v\n", result)
+	}
+	if len(result.Addresses) == 0 {
+		t.Fatal("Expected the host to be resolved")
+	}
+}
+
+func TestTestCheckCert(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	p := &Prober{TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"}}
+
+	req := &observery.CreateCheckRequest{
+		Name:               "cert",
+		Type:               "cert",
+		Interval:           5,
+		Host:               observery.PtrString(u.Hostname()),
+		Port:               observery.PtrInt(port),
+		CertExpirationDays: observery.PtrInt(1000000),
+	}
+	result := p.TestCheck(context.Background(), req)
+	if result.Passed() || result.CertDaysRemaining == nil || *result.CertDaysRemaining <= 0 {
+		t.Fatalf("Expected an expiring cert with its days remaining but got %+v\n", result)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("Expected a single connection but got %d\n", n)
+	}
+}
@@ -0,0 +1,197 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// protocol describes a check type that is up when the server greets us.
+type protocol struct {
+	// port and securePort are the default plain text and implicit TLS ports.
+	port, securePort int
+
+	// greeting is the prefix of the first reply line sent by the server.
+	greeting string
+
+	// starttls upgrades a plain text connection to TLS. Nil if the
+	// protocol doesn't support it.
+	starttls func(c *textConn) error
+}
+
+var protocols = map[string]protocol{
+	"ssh": {port: 22, greeting: "SSH-"},
+	"ftp": {port: 21, securePort: 990, greeting: "220", starttls: func(c *textConn) error {
+		return c.command("AUTH TLS", "234")
+	}},
+	"pop": {port: 110, securePort: 995, greeting: "+OK", starttls: func(c *textConn) error {
+		return c.command("STLS", "+OK")
+	}},
+	"smtp": {port: 25, securePort: 465, greeting: "220", starttls: func(c *textConn) error {
+		if err := c.command("EHLO observery-probe", "250"); err != nil {
+			return err
+		}
+		return c.command("STARTTLS", "220")
+	}},
+	"imap": {port: 143, securePort: 993, greeting: "* OK", starttls: func(c *textConn) error {
+		return c.command("a1 STARTTLS", "a1 OK")
+	}},
+}
+
+// probeBanner connects to the server and waits for its greeting. When
+// req.Secure is set the connection uses implicit TLS on the protocol's
+// secure port, or STARTTLS if req.Port is set to any other port.
+func (p *Prober) probeBanner(ctx context.Context, req *observery.CreateCheckRequest) error {
+	proto := protocols[req.Type]
+
+	var (
+		secure   = req.Secure != nil && *req.Secure
+		starttls = false
+		port     = proto.port
+	)
+	if secure {
+		if proto.starttls == nil {
+			return fmt.Errorf("%s checks can't be secure", req.Type)
+		}
+		port = proto.securePort
+		starttls = req.Port != nil && *req.Port != proto.securePort
+	}
+
+	addr, err := address(req, port)
+	if err != nil {
+		return err
+	}
+
+	d := &net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if secure && !starttls {
+		tlsConn := tls.Client(conn, p.tlsConfig(*req.Host))
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		conn = tlsConn
+	}
+
+	c := newTextConn(conn)
+	if err := c.expect(proto.greeting); err != nil {
+		return err
+	}
+
+	if starttls {
+		if err := proto.starttls(c); err != nil {
+			return err
+		}
+		if err := tls.Client(conn, p.tlsConfig(*req.Host)).Handshake(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// textConn speaks the line based protocols used by the banner checks.
+type textConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newTextConn(conn net.Conn) *textConn {
+	return &textConn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// command sends line and expects a reply starting with prefix.
+func (c *textConn) command(line, prefix string) error {
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		return err
+	}
+	return c.expect(prefix)
+}
+
+// expect reads a reply and checks that it starts with prefix. Multi-line
+// replies using three digit codes, like "250-" continuation lines, are read
+// to the end.
+func (c *textConn) expect(prefix string) error {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if !strings.HasPrefix(line, prefix) {
+			return fmt.Errorf("unexpected reply %q", line)
+		}
+		if len(line) > 3 && line[3] == '-' {
+			if _, err := strconv.Atoi(line[:3]); err == nil {
+				continue
+			}
+		}
+		return nil
+	}
+}
+
+// probePing checks that the host is reachable. A TCP connection is tried
+// first because it doesn't need privileges: both an accepted and a refused
+// connection prove the host is up. If neither port answers the system ping
+// command is used.
+func (p *Prober) probePing(ctx context.Context, req *observery.CreateCheckRequest) error {
+	if req.Host == nil || *req.Host == "" {
+		return errors.New("host is required")
+	}
+
+	ports := []int{80, 443}
+	if req.Port != nil {
+		ports = []int{*req.Port}
+	}
+
+	var tcpErr error
+	for _, port := range ports {
+		d := &net.Dialer{Timeout: 5 * time.Second}
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(*req.Host, strconv.Itoa(port)))
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return nil
+		}
+		tcpErr = err
+	}
+
+	if ctx.Err() != nil {
+		return tcpErr
+	}
+
+	wait := "5"
+	if deadline, ok := ctx.Deadline(); ok {
+		if secs := int(time.Until(deadline).Seconds()); secs > 0 {
+			wait = strconv.Itoa(secs)
+		}
+	}
+	out, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", wait, *req.Host).CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return tcpErr
+		}
+		return fmt.Errorf("host unreachable: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+	"time"
+)
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05 MST")
+	},
+	"duration": func(d time.Duration) string {
+		if d < time.Second {
+			return d.Round(time.Millisecond).String()
+		}
+		return d.Round(time.Second).String()
+	},
+}
+
+// WriteMarkdown renders r as Markdown.
+func WriteMarkdown(w io.Writer, r *Report) error {
+	return markdownTemplate.Execute(w, r)
+}
+
+// WriteHTML renders r as a standalone HTML document.
+func WriteHTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Parse(`# Incident report: {{.CheckName}}
+
+| | |
+|---|---|
+| Outage | {{.OutageID}} |
+| Check | {{.CheckName}} ({{.CheckID}}) |
+| Type | {{.CheckType}} |
+| Target | {{.Target}} |
+| Start | {{date .Start}} |
+| Stop | {{if .Ongoing}}ongoing{{else}}{{date .Stop}}{{end}} |
+| Duration | {{duration .Duration}} |
+| Response time on recovery | {{if .Ongoing}}-{{else}}{{duration .ResponseTime}}{{end}} |
+| In maintenance window | {{if .InMaintenance}}yes{{else}}no{{end}} |
+
+## Details
+
+{{if .Details}}{{.Details}}{{else}}No details were reported.{{end}}
+
+## Notifications
+
+{{range .Contacts}}- {{.Name}}{{if .Type}} ({{.Type}}, after {{duration .Delay}}){{end}}: {{if .Notified}}notified{{else}}not notified{{end}}
+{{else}}No contacts are mapped to this check.
+{{end}}
+## Previous outages in the last 30 days
+
+{{range .PreviousOutages}}- {{date .Start}} for {{duration .Duration}} ({{.ID}})
+{{else}}None.
+{{end}}
+_Generated {{date .Generated}}_
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Incident report: {{.CheckName}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #222; }
+th { text-align: left; padding-right: 2em; }
+</style>
+</head>
+<body>
+<h1>Incident report: {{.CheckName}}</h1>
+<table>
+<tr><th>Outage</th><td>{{.OutageID}}</td></tr>
+<tr><th>Check</th><td>{{.CheckName}} ({{.CheckID}})</td></tr>
+<tr><th>Type</th><td>{{.CheckType}}</td></tr>
+<tr><th>Target</th><td>{{.Target}}</td></tr>
+<tr><th>Start</th><td>{{date .Start}}</td></tr>
+<tr><th>Stop</th><td>{{if .Ongoing}}ongoing{{else}}{{date .Stop}}{{end}}</td></tr>
+<tr><th>Duration</th><td>{{duration .Duration}}</td></tr>
+<tr><th>Response time on recovery</th><td>{{if .Ongoing}}-{{else}}{{duration .ResponseTime}}{{end}}</td></tr>
+<tr><th>In maintenance window</th><td>{{if .InMaintenance}}yes{{else}}no{{end}}</td></tr>
+</table>
+<h2>Details</h2>
+<p>{{if .Details}}{{.Details}}{{else}}No details were reported.{{end}}</p>
+<h2>Notifications</h2>
+<ul>
+{{range .Contacts}}<li>{{.Name}}{{if .Type}} ({{.Type}}, after {{duration .Delay}}){{end}}: {{if .Notified}}notified{{else}}not notified{{end}}</li>
+{{else}}<li>No contacts are mapped to this check.</li>
+{{end}}</ul>
+<h2>Previous outages in the last 30 days</h2>
+<ul>
+{{range .PreviousOutages}}<li>{{date .Start}} for {{duration .Duration}} ({{.ID}})</li>
+{{else}}<li>None.</li>
+{{end}}</ul>
+<p><em>Generated {{date .Generated}}</em></p>
+</body>
+</html>
+`))
@@ -0,0 +1,212 @@
+// Package report generates incident reports for individual observery
+// outages. Reports collect the facts usually copied from the dashboard into
+// a postmortem and can be rendered as Markdown or HTML.
+package report
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Source is the part of observery.Client used to generate a report.
+type Source interface {
+	GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error)
+	GetCheck(ctx context.Context, id string) (*observery.GetCheckResponse, error)
+	GetContact(ctx context.Context, id string) (*observery.GetContactResponse, error)
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+}
+
+// Report holds the facts about a single outage.
+type Report struct {
+	// Generated is when the report was created.
+	Generated time.Time
+
+	// OutageID is the id of the outage being reported on.
+	OutageID string
+
+	// CheckID, CheckName and CheckType describe the check that failed.
+	CheckID   string
+	CheckName string
+	CheckType string
+
+	// Target is the url or host that was checked.
+	Target string
+
+	// Start, Stop and Duration of the outage. Stop is zero and Duration is
+	// measured up to Generated while the outage is ongoing.
+	Start    time.Time
+	Stop     time.Time
+	Duration time.Duration
+	Ongoing  bool
+
+	// ResponseTime is how long the check took to respond on recovery.
+	ResponseTime time.Duration
+
+	// Details is observery's description of what caused the outage.
+	Details string
+
+	// InMaintenance is true if the outage started during one of the
+	// check's maintenance schedules, or maintenance mode is active for an
+	// ongoing outage.
+	InMaintenance bool
+
+	// Contacts lists the contacts mapped to the check and whether they
+	// were notified.
+	Contacts []Contact
+
+	// PreviousOutages are other outages of the same check in the 30 days
+	// before this one started, newest first.
+	PreviousOutages []observery.Outage
+}
+
+// Contact is a contact mapped to the failed check.
+type Contact struct {
+	// ID of the contact.
+	ID string
+
+	// Name of the contact.
+	Name string
+
+	// Type is 'email' or 'sms'. Empty if the contact couldn't be fetched.
+	Type string
+
+	// Delay is the notification delay configured on the check for the
+	// contact's type.
+	Delay time.Duration
+
+	// Notified is true if the outage lasted at least Delay and the contact
+	// is enabled.
+	Notified bool
+}
+
+// Generate builds a Report for the outage with the given id.
+func Generate(ctx context.Context, src Source, outageID string) (*Report, error) {
+	outage, err := src.GetOutage(ctx, outageID)
+	if err != nil {
+		return nil, err
+	}
+	if !outage.Success {
+		return nil, errors.New(outage.Reason)
+	}
+	o := outage.Outage
+
+	check, err := src.GetCheck(ctx, o.CheckID)
+	if err != nil {
+		return nil, err
+	}
+	if !check.Success {
+		return nil, errors.New(check.Reason)
+	}
+	c := check.Check
+
+	r := &Report{
+		Generated:    time.Now(),
+		OutageID:     o.ID,
+		CheckID:      c.ID,
+		CheckName:    c.Name,
+		CheckType:    c.Type,
+		Start:        o.Start,
+		Stop:         o.Stop,
+		Duration:     o.Duration,
+		Ongoing:      o.Ongoing,
+		ResponseTime: o.ResponseTime,
+		Details:      o.Details,
+	}
+	if r.Ongoing {
+		r.Duration = r.Generated.Sub(r.Start)
+	}
+
+	if c.URL != nil {
+		r.Target = *c.URL
+	} else if checks, err := src.ListChecks(ctx); err == nil {
+		for _, lc := range checks.Checks {
+			if lc.ID == c.ID {
+				r.Target = lc.Host
+			}
+		}
+	}
+
+	r.InMaintenance = r.Ongoing && (c.InMaintenance || c.MaintenanceModeActive)
+	for _, s := range c.MaintenanceSchedules {
+		if inSchedule(r.Start, s.Days, s.Start, s.Stop, s.Timezone) {
+			r.InMaintenance = true
+		}
+	}
+
+	for _, cc := range c.Contacts {
+		contact := Contact{ID: cc.ID, Name: cc.Name}
+		resp, err := src.GetContact(ctx, cc.ID)
+		if err == nil && resp.Success {
+			contact.Type = resp.Contact.Type
+			switch contact.Type {
+			case "email":
+				contact.Delay = time.Duration(c.EmailNotificationDelay) * time.Minute
+			case "sms":
+				contact.Delay = time.Duration(c.SmsNotificationDelay) * time.Minute
+			}
+			contact.Notified = resp.Contact.Enabled && !r.InMaintenance && r.Duration >= contact.Delay
+		}
+		r.Contacts = append(r.Contacts, contact)
+	}
+
+	outages, err := src.ListOutages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !outages.Success {
+		return nil, errors.New(outages.Reason)
+	}
+	since := r.Start.AddDate(0, 0, -30)
+	for _, prev := range outages.Outages {
+		if prev.CheckID == r.CheckID && prev.ID != r.OutageID && prev.Start.Before(r.Start) && prev.Start.After(since) {
+			r.PreviousOutages = append(r.PreviousOutages, prev)
+		}
+	}
+	sort.Slice(r.PreviousOutages, func(i, j int) bool {
+		return r.PreviousOutages[i].Start.After(r.PreviousOutages[j].Start)
+	})
+
+	return r, nil
+}
+
+// inSchedule reports whether t falls inside a maintenance schedule. Days is
+// a comma-separated list of weekdays, either as names ("mon" or "monday")
+// or numbers with Sunday as 0. Only the time of day of start and stop is
+// used. Schedules that can't be understood never match.
+func inSchedule(t time.Time, days string, start, stop time.Time, timezone string) bool {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	matched := false
+	for _, day := range strings.Split(days, ",") {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if n, err := strconv.Atoi(day); err == nil {
+			matched = matched || time.Weekday(n) == t.Weekday()
+		} else if len(day) >= 3 {
+			matched = matched || strings.HasPrefix(strings.ToLower(t.Weekday().String()), day[:3])
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	clock := func(t time.Time) int {
+		return t.Hour()*60 + t.Minute()
+	}
+	from, to, now := clock(start), clock(stop), clock(t)
+	if from <= to {
+		return now >= from && now < to
+	}
+	// The window wraps around midnight.
+	return now >= from || now < to
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type fakeSource struct {
+	outage  *observery.GetOutageResponse
+	check   *observery.GetCheckResponse
+	outages *observery.ListOutagesResponse
+}
+
+func (f *fakeSource) GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error) {
+	return f.outage, nil
+}
+
+func (f *fakeSource) GetCheck(ctx context.Context, id string) (*observery.GetCheckResponse, error) {
+	return f.check, nil
+}
+
+func (f *fakeSource) GetContact(ctx context.Context, id string) (*observery.GetContactResponse, error) {
+	resp := &observery.GetContactResponse{Success: true}
+	resp.Contact.ID = id
+	resp.Contact.Enabled = true
+	resp.Contact.Type = map[string]string{"c1": "email", "c2": "sms"}[id]
+	return resp, nil
+}
+
+func (f *fakeSource) ListChecks(ctx context.Context) (*observery.ListChecksResponse, error) {
+	return &observery.ListChecksResponse{Success: true}, nil
+}
+
+func (f *fakeSource) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return f.outages, nil
+}
+
+func TestGenerate(t *testing.T) {
+	start := time.Date(2019, 10, 22, 3, 0, 0, 0, time.UTC)
+
+	src := &fakeSource{
+		outage:  &observery.GetOutageResponse{Success: true},
+		check:   &observery.GetCheckResponse{},
+		outages: &observery.ListOutagesResponse{Success: true},
+	}
+	src.outage.Outage.ID = "o1"
+	src.outage.Outage.CheckID = "c"
+	src.outage.Outage.Start = start
+	src.outage.Outage.Stop = start.Add(10 * time.Minute)
+	src.outage.Outage.Duration = 10 * time.Minute
+	src.outage.Outage.ResponseTime = 250 * time.Millisecond
+	src.outage.Outage.Details = "Connection refused"
+
+	err := json.Unmarshal([]byte(`{
+		"success": true,
+		"result": {
+			"id": "c", "name": "api", "type": "http", "url": "https://example.com",
+			"emailNotificationDelay": 5, "smsNotificationDelay": 15,
+			"maintenanceSchedules": [{"days": "tue", "start": "2019-01-01T02:00:00Z", "stop": "2019-01-01T02:30:00Z", "timezone": "UTC"}],
+			"contacts": [{"id": "c1", "name": "Ops"}, {"id": "c2", "name": "On call"}]
+		}
+	}`), src.check)
+	if err != nil {
+		t.Fatalf("Error decoding check: %s\n", err)
+	}
+
+	src.outages.Outages = []observery.Outage{
+		{ID: "o1", CheckID: "c", Start: start},
+		{ID: "o0", CheckID: "c", Start: start.AddDate(0, 0, -3), Duration: time.Minute},
+		{ID: "old", CheckID: "c", Start: start.AddDate(0, 0, -40)},
+		{ID: "other", CheckID: "x", Start: start.AddDate(0, 0, -1)},
+	}
+
+	r, err := Generate(context.Background(), src, "o1")
+	if err != nil {
+		t.Fatalf("Error generating report: %s\n", err)
+	}
+
+	if r.Target != "https://example.com" || r.InMaintenance {
+		t.Fatalf("Unexpected report: %+v\n", r)
+	}
+	if len(r.Contacts) != 2 || !r.Contacts[0].Notified || r.Contacts[1].Notified {
+		t.Fatalf("Expected only the email contact to be notified but got %+v\n", r.Contacts)
+	}
+	if len(r.PreviousOutages) != 1 || r.PreviousOutages[0].ID != "o0" {
+		t.Fatalf("Expected one previous outage but got %+v\n", r.PreviousOutages)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, r); err != nil {
+		t.Fatalf("Error rendering markdown: %s\n", err)
+	}
+	for _, want := range []string{"| Duration | 10m0s |", "| Response time on recovery | 250ms |", "- Ops (email, after 5m0s): notified"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected report to contain %q\n", want)
+		}
+	}
+
+	if !inSchedule(start.Add(-50*time.Minute), "tue", time.Date(0, 1, 1, 2, 0, 0, 0, time.UTC), time.Date(0, 1, 1, 2, 30, 0, 0, time.UTC), "UTC") {
+		t.Fatal("Expected 02:10 on a tuesday to be in the maintenance window")
+	}
+
+	src.outages = &observery.ListOutagesResponse{Reason: "rate limit exceeded"}
+	if _, err := Generate(context.Background(), src, "o1"); err == nil || err.Error() != "rate limit exceeded" {
+		t.Fatalf("Expected the failed outage list to be reported but got %v\n", err)
+	}
+}
@@ -0,0 +1,261 @@
+package routing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is the root of a routing configuration file.
+//
+//	route:
+//	  receiver: email
+//	  dedup: 10m
+//	  routes:
+//	    - match:
+//	        name: "api-*"
+//	        states: [down]
+//	        statusCodes: ["500-599"]
+//	        time: "22:00-06:00"
+//	        timezone: Europe/Berlin
+//	      receiver: pager
+//	      continue: true
+type Config struct {
+	// Route is the root of the routing tree. It matches every webhook.
+	Route *Route `yaml:"route"`
+}
+
+// Route is a node in the routing tree.
+type Route struct {
+	// Match holds the conditions a webhook must meet for this route.
+	Match Match `yaml:"match"`
+
+	// Receiver is the name of the notifier webhooks matching this route
+	// are sent to. Inherited from the parent route when empty.
+	Receiver string `yaml:"receiver"`
+
+	// Dedup suppresses repeated notifications for the same check, state
+	// and receiver within this window. Inherited from the parent route
+	// when zero.
+	Dedup time.Duration `yaml:"dedup"`
+
+	// Continue makes the parent keep evaluating its following routes after
+	// this one matched. By default the first matching route wins.
+	Continue bool `yaml:"continue"`
+
+	// Routes are child routes. A webhook that matches this route is
+	// handled by the matching children, or by this route if none match.
+	Routes []*Route `yaml:"routes"`
+}
+
+// Match holds the conditions of a route. Empty conditions match everything.
+type Match struct {
+	// Name is a glob pattern matched against the check name, as
+	// implemented by path.Match.
+	Name string `yaml:"name"`
+
+	// Types lists the check types that match.
+	Types []string `yaml:"types"`
+
+	// States lists the states that match.
+	States []string `yaml:"states"`
+
+	// TimedOut only matches webhooks with the same TimedOut value when set.
+	TimedOut *bool `yaml:"timedOut"`
+
+	// StatusCodes lists HTTP status codes or ranges like "500-599".
+	StatusCodes []string `yaml:"statusCodes"`
+
+	// Time is a time of day range like "09:00-17:00". Ranges may wrap
+	// around midnight.
+	Time string `yaml:"time"`
+
+	// Days lists weekdays like "mon" or "saturday" that match.
+	Days []string `yaml:"days"`
+
+	// Timezone is the location Time and Days are evaluated in. Defaults
+	// to UTC.
+	Timezone string `yaml:"timezone"`
+
+	codes    [][2]int
+	from, to int
+	location *time.Location
+	hasTime  bool
+}
+
+// LoadConfig reads and validates a YAML routing configuration.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses and validates a YAML routing configuration.
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	if config.Route == nil {
+		return nil, fmt.Errorf("routing: config has no route")
+	}
+	if config.Route.Receiver == "" {
+		return nil, fmt.Errorf("routing: the root route needs a receiver")
+	}
+	if err := config.Route.compile("route"); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// compile validates the route and its children and prepares their
+// matchers.
+func (r *Route) compile(where string) error {
+	m := &r.Match
+
+	if m.Name != "" {
+		if _, err := path.Match(m.Name, ""); err != nil {
+			return fmt.Errorf("routing: %s: invalid name pattern %q", where, m.Name)
+		}
+	}
+
+	for _, c := range m.StatusCodes {
+		lo, hi := c, c
+		if i := strings.Index(c, "-"); i >= 0 {
+			lo, hi = c[:i], c[i+1:]
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from > to {
+			return fmt.Errorf("routing: %s: invalid status code range %q", where, c)
+		}
+		m.codes = append(m.codes, [2]int{from, to})
+	}
+
+	m.location = time.UTC
+	if m.Timezone != "" {
+		loc, err := time.LoadLocation(m.Timezone)
+		if err != nil {
+			return fmt.Errorf("routing: %s: %s", where, err)
+		}
+		m.location = loc
+	}
+
+	if m.Time != "" {
+		parts := strings.Split(m.Time, "-")
+		if len(parts) != 2 {
+			return fmt.Errorf("routing: %s: invalid time range %q", where, m.Time)
+		}
+		var err error
+		if m.from, err = parseClock(parts[0]); err != nil {
+			return fmt.Errorf("routing: %s: invalid time range %q", where, m.Time)
+		}
+		if m.to, err = parseClock(parts[1]); err != nil {
+			return fmt.Errorf("routing: %s: invalid time range %q", where, m.Time)
+		}
+		m.hasTime = true
+	}
+
+	for _, d := range m.Days {
+		if weekday(d) < 0 {
+			return fmt.Errorf("routing: %s: invalid day %q", where, d)
+		}
+	}
+
+	for i, child := range r.Routes {
+		if err := child.compile(fmt.Sprintf("%s.routes[%d]", where, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether hook, received at t, meets all conditions.
+func (m *Match) matches(hook *observery.Webhook, t time.Time) bool {
+	if m.Name != "" {
+		if ok, _ := path.Match(m.Name, hook.CheckName); !ok {
+			return false
+		}
+	}
+	if len(m.Types) > 0 && !contains(m.Types, hook.CheckType) {
+		return false
+	}
+	if len(m.States) > 0 && !contains(m.States, hook.State) {
+		return false
+	}
+	if m.TimedOut != nil && *m.TimedOut != hook.TimedOut {
+		return false
+	}
+
+	if len(m.codes) > 0 {
+		ok := false
+		for _, c := range m.codes {
+			ok = ok || (hook.HTTPStatusCode >= c[0] && hook.HTTPStatusCode <= c[1])
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	t = t.In(m.location)
+	if len(m.Days) > 0 {
+		ok := false
+		for _, d := range m.Days {
+			ok = ok || weekday(d) == int(t.Weekday())
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if m.hasTime {
+		now := t.Hour()*60 + t.Minute()
+		if m.from <= m.to {
+			return now >= m.from && now < m.to
+		}
+		return now >= m.from || now < m.to
+	}
+
+	return true
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// weekday returns the time.Weekday for names like "mon" or "Monday", or
+// -1 if the name isn't a weekday.
+func weekday(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return -1
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if full := strings.ToLower(d.String()); strings.HasPrefix(full, name) {
+			return int(d)
+		}
+	}
+	return -1
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
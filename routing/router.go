@@ -0,0 +1,230 @@
+// Package routing decides who gets notified about observery webhooks.
+// Webhooks are evaluated against a tree of routes loaded from YAML, in the
+// spirit of the Alertmanager routing tree, and dispatched to the notifiers
+// registered for the matching receivers.
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Notifier sends a notification about a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, hook *observery.Webhook) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, hook *observery.Webhook) error
+
+// Notify calls f(ctx, hook).
+func (f NotifierFunc) Notify(ctx context.Context, hook *observery.Webhook) error {
+	return f(ctx, hook)
+}
+
+// Router dispatches webhooks according to a Config.
+type Router struct {
+	mu        sync.RWMutex
+	config    *Config
+	notifiers map[string]Notifier
+
+	dedupMu sync.Mutex
+	sent    map[string]time.Time
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewRouter creates a Router for config. notifiers maps receiver names
+// used in the config to their Notifier.
+func NewRouter(config *Config, notifiers map[string]Notifier) (*Router, error) {
+	r := &Router{
+		notifiers: notifiers,
+		sent:      map[string]time.Time{},
+		now:       time.Now,
+	}
+	if err := r.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetConfig replaces the routing configuration. The current configuration
+// is kept if config references unknown receivers.
+func (r *Router) SetConfig(config *Config) error {
+	if err := r.checkReceivers(config.Route); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.config = config
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Router) checkReceivers(route *Route) error {
+	if route.Receiver != "" {
+		if _, ok := r.notifiers[route.Receiver]; !ok {
+			return fmt.Errorf("routing: unknown receiver %q", route.Receiver)
+		}
+	}
+	for _, child := range route.Routes {
+		if err := r.checkReceivers(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns an http.HandlerFunc that routes every webhook sent by
+// observery.com. Routing errors are passed to onError, which may be nil.
+func (r *Router) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = r.Route(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// match is a receiver selected for a webhook along with its dedup window.
+type match struct {
+	receiver string
+	dedup    time.Duration
+}
+
+// Route sends hook to the notifiers of every matching receiver. Each
+// receiver is notified at most once per webhook. All notifiers are called
+// even if one fails; the returned error lists every failure.
+func (r *Router) Route(ctx context.Context, hook *observery.Webhook) error {
+	r.mu.RLock()
+	config := r.config
+	r.mu.RUnlock()
+
+	now := r.now()
+	matches := walk(config.Route, hook, now, match{})
+
+	var errs []string
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if seen[m.receiver] || r.duplicate(m, hook, now) {
+			continue
+		}
+		seen[m.receiver] = true
+
+		if err := r.notifiers[m.receiver].Notify(ctx, hook); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", m.receiver, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("routing: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// walk returns the receivers of route for hook, or nil if route doesn't
+// match. inherited holds the settings of the parent route.
+func walk(route *Route, hook *observery.Webhook, now time.Time, inherited match) []match {
+	if !route.Match.matches(hook, now) {
+		return nil
+	}
+
+	m := inherited
+	if route.Receiver != "" {
+		m.receiver = route.Receiver
+	}
+	if route.Dedup != 0 {
+		m.dedup = route.Dedup
+	}
+
+	var matches []match
+	for _, child := range route.Routes {
+		found := walk(child, hook, now, m)
+		if len(found) == 0 {
+			continue
+		}
+		matches = append(matches, found...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(matches) == 0 {
+		matches = []match{m}
+	}
+	return matches
+}
+
+// duplicate reports whether the same check and state were sent to the
+// receiver within its dedup window, and records the notification if not.
+func (r *Router) duplicate(m match, hook *observery.Webhook, now time.Time) bool {
+	if m.dedup <= 0 {
+		return false
+	}
+
+	key := m.receiver + "\x00" + hook.CheckID + "\x00" + hook.State
+
+	r.dedupMu.Lock()
+	defer r.dedupMu.Unlock()
+
+	if last, ok := r.sent[key]; ok && now.Sub(last) < m.dedup {
+		return true
+	}
+	r.sent[key] = now
+
+	// Forget entries that can no longer suppress anything.
+	for k, t := range r.sent {
+		if now.Sub(t) > 24*time.Hour {
+			delete(r.sent, k)
+		}
+	}
+	return false
+}
+
+// WatchConfig reloads the config file whenever its modification time
+// changes, checking every interval until ctx is done. Invalid configs are
+// passed to onError, which may be nil, and the current config is kept.
+func (r *Router) WatchConfig(ctx context.Context, file string, interval time.Duration, onError func(error)) {
+	var modTime time.Time
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime()
+	}
+
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		config, err := LoadConfig(file)
+		if err == nil {
+			err = r.SetConfig(config)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+const testConfig = `
+route:
+  receiver: email
+  routes:
+    - match:
+        name: "db-*"
+        states: [down]
+      receiver: pager
+      dedup: 10m
+      continue: true
+    - match:
+        statusCodes: ["500-599"]
+        time: "22:00-06:00"
+      receiver: chat
+    - match:
+        types: [cert]
+      receiver: chat
+`
+
+func TestRoute(t *testing.T) {
+	config, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("Error parsing config: %s\n", err)
+	}
+
+	got := map[string]int{}
+	notifier := func(name string) Notifier {
+		return NotifierFunc(func(ctx context.Context, hook *observery.Webhook) error {
+			got[name]++
+			return nil
+		})
+	}
+
+	r, err := NewRouter(config, map[string]Notifier{
+		"email": notifier("email"),
+		"pager": notifier("pager"),
+		"chat":  notifier("chat"),
+	})
+	if err != nil {
+		t.Fatalf("Error creating router: %s\n", err)
+	}
+
+	now := time.Date(2019, 10, 1, 23, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	tests := []struct {
+		hook *observery.Webhook
+		want map[string]int
+	}{
+		// Continues from pager to the status code route.
+		{&observery.Webhook{CheckID: "1", CheckName: "db-main", State: "down", HTTPStatusCode: 503}, map[string]int{"pager": 1, "chat": 1}},
+		// Deduplicated for the pager but not for chat.
+		{&observery.Webhook{CheckID: "1", CheckName: "db-main", State: "down", HTTPStatusCode: 503}, map[string]int{"chat": 1}},
+		// Nothing matches so the root receiver gets it.
+		{&observery.Webhook{CheckID: "2", CheckName: "web", State: "down", HTTPStatusCode: 404}, map[string]int{"email": 1}},
+		// The first matching route without continue stops evaluation.
+		{&observery.Webhook{CheckID: "3", CheckName: "cert", CheckType: "cert", State: "down", HTTPStatusCode: 500}, map[string]int{"chat": 1}},
+	}
+	for i, test := range tests {
+		got = map[string]int{}
+		if err := r.Route(context.Background(), test.hook); err != nil {
+			t.Fatalf("%d: Error routing: %s\n", i, err)
+		}
+		if len(got) != len(test.want) {
+			t.Fatalf("%d: Expected %v but got %v\n", i, test.want, got)
+		}
+		for k, v := range test.want {
+			if got[k] != v {
+				t.Fatalf("%d: Expected %v but got %v\n", i, test.want, got)
+			}
+		}
+	}
+
+	now = now.Add(12 * time.Hour)
+	got = map[string]int{}
+	r.Route(context.Background(), &observery.Webhook{CheckID: "1", CheckName: "db-main", State: "down", HTTPStatusCode: 503})
+	if len(got) != 1 || got["pager"] != 1 {
+		t.Fatalf("Expected only pager outside of the time window but got %v\n", got)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		"route: {}",
+		"route: {receiver: a, routes: [{match: {time: '9-5'}}]}",
+		"route: {receiver: a, routes: [{match: {statusCodes: ['599-500']}}]}",
+		"route: {receiver: a, routes: [{match: {days: [xyz]}}]}",
+		"route: {receiver: a, unknown: true}",
+	} {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Errorf("Expected an error for %q\n", config)
+		}
+	}
+
+	config, _ := ParseConfig([]byte("route: {receiver: missing}"))
+	if _, err := NewRouter(config, nil); err == nil {
+		t.Error("Expected an error for an unknown receiver")
+	}
+}
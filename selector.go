@@ -0,0 +1,117 @@
+package observery
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Selector picks checks by their fields. It is parsed from a comma-separated
+// list of terms like "type=http,state!=up,name=api-*". Every term has to
+// match. Values are glob patterns as implemented by path.Match. The fields
+// id, name, type, state, active, url, host and account can be used.
+type Selector []SelectorTerm
+
+// SelectorTerm is a single condition of a Selector.
+type SelectorTerm struct {
+	// Field is the name of the check field.
+	Field string
+
+	// Value is the glob pattern the field is matched against.
+	Value string
+
+	// Negate inverts the match, for terms written with "!=".
+	Negate bool
+}
+
+// ParseSelector parses a selector. An empty string selects all checks.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		i := strings.Index(term, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("observery: invalid selector term %q", term)
+		}
+
+		t := SelectorTerm{
+			Field: strings.TrimSpace(term[:i]),
+			Value: strings.TrimSpace(term[i+1:]),
+		}
+		if strings.HasSuffix(t.Field, "!") {
+			t.Field = strings.TrimSpace(strings.TrimSuffix(t.Field, "!"))
+			t.Negate = true
+		}
+		if _, ok := checkField(Check{}, t.Field); !ok {
+			return nil, fmt.Errorf("observery: unknown selector field %q", t.Field)
+		}
+		if _, err := path.Match(t.Value, ""); err != nil {
+			return nil, fmt.Errorf("observery: invalid selector pattern %q", t.Value)
+		}
+		sel = append(sel, t)
+	}
+	return sel, nil
+}
+
+// Match reports whether check matches every term of the selector.
+func (s Selector) Match(check Check) bool {
+	for _, t := range s {
+		value, _ := checkField(check, t.Field)
+		ok, _ := path.Match(t.Value, value)
+		if ok == t.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the checks that match the selector.
+func (s Selector) Filter(checks []Check) []Check {
+	var matched []Check
+	for _, c := range checks {
+		if s.Match(c) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// String returns the selector in the syntax accepted by ParseSelector.
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, t := range s {
+		op := "="
+		if t.Negate {
+			op = "!="
+		}
+		terms[i] = t.Field + op + t.Value
+	}
+	return strings.Join(terms, ",")
+}
+
+func checkField(c Check, field string) (string, bool) {
+	switch field {
+	case "id":
+		return c.ID, true
+	case "name":
+		return c.Name, true
+	case "type":
+		return c.Type, true
+	case "state":
+		return c.State, true
+	case "active":
+		return strconv.FormatBool(c.Active), true
+	case "url":
+		return c.URL, true
+	case "host":
+		return c.Host, true
+	case "account":
+		return c.Account, true
+	}
+	return "", false
+}
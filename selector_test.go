@@ -0,0 +1,40 @@
+package observery
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	checks := []Check{
+		{ID: "1", Name: "api-eu", Type: "http", State: "down", Active: true},
+		{ID: "2", Name: "api-us", Type: "http", State: "up", Active: true},
+		{ID: "3", Name: "db", Type: "ping", State: "down", Active: false},
+	}
+
+	for _, test := range []struct {
+		selector string
+		ids      string
+	}{
+		{"", "123"},
+		{"type=http", "12"},
+		{"name=api-*, state!=up", "1"},
+		{"active=false", "3"},
+		{"state=down,type!=ping", "1"},
+	} {
+		sel, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s\n", test.selector, err)
+		}
+		ids := ""
+		for _, c := range sel.Filter(checks) {
+			ids += c.ID
+		}
+		if ids != test.ids {
+			t.Errorf("Expected %q to select %s but got %s\n", test.selector, test.ids, ids)
+		}
+	}
+
+	for _, bad := range []string{"state", "color=red", "name=[a"} {
+		if _, err := ParseSelector(bad); err == nil {
+			t.Errorf("Expected an error parsing %q\n", bad)
+		}
+	}
+}
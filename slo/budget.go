@@ -0,0 +1,133 @@
+package slo
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+// Budget is the state of an objective's error budget.
+type Budget struct {
+	// Objective is the name of the objective.
+	Objective string `json:"objective"`
+
+	// Target is the availability goal in percent.
+	Target float64 `json:"target"`
+
+	// Window is the period the budget covers.
+	Window time.Duration `json:"window"`
+
+	// Availability is the achieved availability within the window in
+	// percent.
+	Availability float64 `json:"availability"`
+
+	// Allowed is the downtime the target allows within the window.
+	Allowed time.Duration `json:"allowed"`
+
+	// Spent is the downtime within the window.
+	Spent time.Duration `json:"spent"`
+
+	// Remaining is Allowed minus Spent. Negative once the budget is
+	// exhausted.
+	Remaining time.Duration `json:"remaining"`
+
+	// RemainingRatio is Remaining as a fraction of Allowed.
+	RemainingRatio float64 `json:"remainingRatio"`
+}
+
+// Exhausted reports whether the objective missed its target.
+func (b Budget) Exhausted() bool {
+	return b.Remaining < 0
+}
+
+// Compute returns the error budget of the objective at now, based on the
+// given outage history. Ongoing outages count up to now.
+func Compute(o Objective, outages []observery.Outage, now time.Time) Budget {
+	o.setDefaults()
+
+	var (
+		window  = time.Duration(o.Window)
+		spent   = Downtime(o, outages, now.Add(-window), now)
+		allowed = time.Duration(float64(window) * o.budget()).Round(time.Millisecond)
+	)
+
+	b := Budget{
+		Objective:    o.Name,
+		Target:       o.Target,
+		Window:       window,
+		Availability: 100 * (1 - float64(spent)/float64(window)),
+		Allowed:      allowed,
+		Spent:        spent,
+		Remaining:    allowed - spent,
+	}
+	if allowed > 0 {
+		b.RemainingRatio = float64(b.Remaining) / float64(allowed)
+	}
+	return b
+}
+
+// BurnRate returns how fast the error budget was spent during the window
+// before now, relative to the rate that uses up the budget exactly over
+// the objective's window.
+func BurnRate(o Objective, outages []observery.Outage, window time.Duration, now time.Time) float64 {
+	down := Downtime(o, outages, now.Add(-window), now)
+	return burnRate(o, down, window)
+}
+
+func burnRate(o Objective, down, window time.Duration) float64 {
+	if window <= 0 || o.budget() <= 0 {
+		return 0
+	}
+	return float64(down) / float64(window) / o.budget()
+}
+
+// Downtime returns how long any check of the objective was down between
+// from and to. Overlapping outages of different checks are only counted
+// once. Ongoing outages count up to to.
+func Downtime(o Objective, outages []observery.Outage, from, to time.Time) time.Duration {
+	var periods []period
+	for _, out := range outages {
+		if !o.covers(out.CheckID, out.CheckName) {
+			continue
+		}
+		stop := out.Stop
+		if out.Ongoing || stop.IsZero() {
+			stop = to
+		}
+		periods = append(periods, period{out.Start, stop})
+	}
+	return union(periods, from, to)
+}
+
+// period is a time span during which a check was down.
+type period struct {
+	start, stop time.Time
+}
+
+// union returns the total length of the periods clipped to from and to,
+// counting overlaps once.
+func union(periods []period, from, to time.Time) time.Duration {
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].start.Before(periods[j].start)
+	})
+
+	var (
+		total time.Duration
+		end   = from
+	)
+	for _, p := range periods {
+		start, stop := p.start, p.stop
+		if start.Before(end) {
+			start = end
+		}
+		if stop.After(to) {
+			stop = to
+		}
+		if stop.After(start) {
+			total += stop.Sub(start)
+			end = stop
+		}
+	}
+	return total
+}
@@ -0,0 +1,277 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/notify"
+)
+
+// Alert is a firing burn rate alert.
+type Alert struct {
+	// Objective is the name of the objective that is burning.
+	Objective string `json:"objective"`
+
+	// BurnAlert is the alert definition that fired.
+	BurnAlert BurnAlert `json:"burnAlert"`
+
+	// LongRate and ShortRate are the burn rates over the long and the
+	// short window as of the last evaluation.
+	LongRate  float64 `json:"longRate"`
+	ShortRate float64 `json:"shortRate"`
+
+	// Since is when the alert started firing.
+	Since time.Time `json:"since"`
+}
+
+// Alerter raises burn rate alerts from live state changes. It notifies Next
+// with a "burning" webhook when an alert starts firing and a
+// "burn-resolved" webhook when it stops. The webhooks carry the objective
+// name as CheckID and CheckName.
+type Alerter struct {
+	next       notify.Notifier
+	objectives []Objective
+
+	mu     sync.Mutex
+	checks map[string]*checkHistory
+	firing map[alertKey]*Alert
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+type checkHistory struct {
+	id, name  string
+	periods   []period
+	downSince time.Time
+}
+
+type alertKey struct {
+	objective string
+	index     int
+}
+
+// NewAlerter creates an Alerter for the objectives that notifies next.
+func NewAlerter(objectives []Objective, next notify.Notifier) *Alerter {
+	a := &Alerter{
+		next:   next,
+		checks: map[string]*checkHistory{},
+		firing: map[alertKey]*Alert{},
+		now:    time.Now,
+	}
+	for _, o := range objectives {
+		o.setDefaults()
+		a.objectives = append(a.objectives, o)
+	}
+	return a
+}
+
+// Seed loads recent outages, for example from a store.Store, so burn rates
+// are correct right after a restart.
+func (a *Alerter) Seed(outages []observery.Outage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, o := range outages {
+		h := a.history(o.CheckID, o.CheckName)
+		if o.Ongoing || o.Stop.IsZero() {
+			if h.downSince.IsZero() || o.Start.Before(h.downSince) {
+				h.downSince = o.Start
+			}
+			continue
+		}
+		h.periods = append(h.periods, period{o.Start, o.Stop})
+	}
+}
+
+// Handler returns an http.HandlerFunc that feeds every webhook sent by
+// observery.com into the Alerter. Errors are passed to onError, which may
+// be nil.
+func (a *Alerter) Handler(onError func(error)) http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			err = a.Observe(context.Background(), hook)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// ObserveStateChange feeds an event from Client.WatchChecks into the
+// Alerter.
+func (a *Alerter) ObserveStateChange(ctx context.Context, e observery.StateChangeEvent) error {
+	return a.Observe(ctx, e.Webhook())
+}
+
+// Observe records the state of the check and evaluates the alerts.
+func (a *Alerter) Observe(ctx context.Context, hook *observery.Webhook) error {
+	a.mu.Lock()
+	now := a.now()
+	h := a.history(hook.CheckID, hook.CheckName)
+	switch {
+	case hook.State == "down" && h.downSince.IsZero():
+		h.downSince = now
+	case hook.State == "up" && !h.downSince.IsZero():
+		h.periods = append(h.periods, period{h.downSince, now})
+		h.downSince = time.Time{}
+	}
+	a.mu.Unlock()
+
+	return a.Evaluate(ctx)
+}
+
+// Evaluate recomputes the burn rates and sends notifications for alerts
+// that started or stopped firing. Since downtime keeps adding up while a
+// check is down, it should be called periodically; Run does that.
+func (a *Alerter) Evaluate(ctx context.Context) error {
+	a.mu.Lock()
+	var (
+		now   = a.now()
+		sends []*observery.Webhook
+	)
+	a.prune(now)
+
+	for _, o := range a.objectives {
+		periods := a.periods(o, now)
+		for i, b := range o.BurnAlerts {
+			var (
+				long      = time.Duration(b.Long)
+				short     = time.Duration(b.Short)
+				longRate  = burnRate(o, union(periods, now.Add(-long), now), long)
+				shortRate = burnRate(o, union(periods, now.Add(-short), now), short)
+				key       = alertKey{o.Name, i}
+				alert     = a.firing[key]
+			)
+
+			switch fire := longRate >= b.Factor && shortRate >= b.Factor; {
+			case fire && alert == nil:
+				alert = &Alert{Objective: o.Name, BurnAlert: b, Since: now}
+				a.firing[key] = alert
+				sends = append(sends, burnHook(alert, longRate, shortRate, "burning"))
+			case !fire && alert != nil:
+				delete(a.firing, key)
+				sends = append(sends, burnHook(alert, longRate, shortRate, "burn-resolved"))
+			}
+			if alert != nil {
+				alert.LongRate, alert.ShortRate = longRate, shortRate
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	var errs []string
+	for _, hook := range sends {
+		if err := a.next.Notify(ctx, hook); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("slo: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Alerts returns the firing alerts ordered by objective.
+func (a *Alerter) Alerts() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(a.firing))
+	for _, alert := range a.firing {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Objective != alerts[j].Objective {
+			return alerts[i].Objective < alerts[j].Objective
+		}
+		return alerts[i].BurnAlert.Long < alerts[j].BurnAlert.Long
+	})
+	return alerts
+}
+
+// Run calls Evaluate every interval until ctx is done. Errors are passed to
+// onError, which may be nil.
+func (a *Alerter) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := a.Evaluate(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// history returns the history of a check, creating it if needed. Must be
+// called with a.mu held.
+func (a *Alerter) history(id, name string) *checkHistory {
+	h, ok := a.checks[id]
+	if !ok {
+		h = &checkHistory{id: id}
+		a.checks[id] = h
+	}
+	if name != "" {
+		h.name = name
+	}
+	return h
+}
+
+// periods returns the down periods of the checks of o, with ongoing ones
+// ending at now. Must be called with a.mu held.
+func (a *Alerter) periods(o Objective, now time.Time) []period {
+	var periods []period
+	for _, h := range a.checks {
+		if !o.covers(h.id, h.name) {
+			continue
+		}
+		periods = append(periods, h.periods...)
+		if !h.downSince.IsZero() {
+			periods = append(periods, period{h.downSince, now})
+		}
+	}
+	return periods
+}
+
+// prune drops down periods older than the longest alert window. Must be
+// called with a.mu held.
+func (a *Alerter) prune(now time.Time) {
+	var longest time.Duration
+	for _, o := range a.objectives {
+		for _, b := range o.BurnAlerts {
+			if time.Duration(b.Long) > longest {
+				longest = time.Duration(b.Long)
+			}
+		}
+	}
+
+	for _, h := range a.checks {
+		kept := h.periods[:0]
+		for _, p := range h.periods {
+			if now.Sub(p.stop) <= longest {
+				kept = append(kept, p)
+			}
+		}
+		h.periods = kept
+	}
+}
+
+func burnHook(alert *Alert, longRate, shortRate float64, state string) *observery.Webhook {
+	b := alert.BurnAlert
+	return &observery.Webhook{
+		CheckID:   alert.Objective,
+		CheckName: alert.Objective,
+		State:     state,
+		Details: fmt.Sprintf("burn rate %.1fx over %s and %.1fx over %s, alerting at %.1fx",
+			longRate, b.Long, shortRate, b.Short, b.Factor),
+	}
+}
@@ -0,0 +1,188 @@
+// Package slo tracks service level objectives for observery checks. It
+// computes the remaining error budget of an objective from the outage
+// history and raises multi-window burn rate alerts from live state
+// changes, so a fast burn is noticed long before the budget is gone.
+package slo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is the root of an SLO configuration file.
+//
+//	objectives:
+//	  - name: checkout
+//	    checks: [api, payments]
+//	    target: 99.9
+//	    window: 30d
+//	    burnAlerts:
+//	      - {long: 1h, short: 5m, factor: 14.4}
+//	      - {long: 6h, short: 30m, factor: 6}
+type Config struct {
+	// Objectives are the defined SLOs.
+	Objectives []Objective `yaml:"objectives"`
+}
+
+// Objective is a single SLO over one check or a group of checks. A group is
+// down while any of its checks is down.
+type Objective struct {
+	// Name of the objective.
+	Name string `yaml:"name"`
+
+	// Checks lists the ids or names of the checks the objective covers.
+	Checks []string `yaml:"checks"`
+
+	// Target is the availability goal in percent, like 99.9.
+	Target float64 `yaml:"target"`
+
+	// Window is the rolling period the target applies to. Defaults to 30
+	// days.
+	Window Duration `yaml:"window"`
+
+	// BurnAlerts are the burn rate alerts of the objective. Defaults to
+	// DefaultBurnAlerts.
+	BurnAlerts []BurnAlert `yaml:"burnAlerts"`
+}
+
+// BurnAlert fires when the error budget burns at least Factor times faster
+// than sustainable over both the Long and the Short window. The short
+// window makes the alert stop soon after the burn stops.
+type BurnAlert struct {
+	// Long is the main window the burn rate is measured over.
+	Long Duration `yaml:"long"`
+
+	// Short is the confirmation window.
+	Short Duration `yaml:"short"`
+
+	// Factor is the burn rate at which the alert fires. A burn rate of 1
+	// uses up the budget exactly at the end of the window.
+	Factor float64 `yaml:"factor"`
+}
+
+// DefaultBurnAlerts are the classic fast burn alerts: 2% of a 30 day budget
+// spent in one hour or 5% in six hours.
+var DefaultBurnAlerts = []BurnAlert{
+	{Long: Duration(time.Hour), Short: Duration(5 * time.Minute), Factor: 14.4},
+	{Long: Duration(6 * time.Hour), Short: Duration(30 * time.Minute), Factor: 6},
+}
+
+// Duration is a time.Duration that also accepts days, like "30d", in YAML.
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration like "30d", "1h" or "90m".
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		*d = Duration(days * float64(24*time.Hour))
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// String formats the duration in days when it is a whole number of days,
+// and otherwise like time.Duration without trailing zero units, like "5m"
+// or "6h".
+func (d Duration) String() string {
+	day := 24 * time.Hour
+	if v := time.Duration(d); v > 0 && v%day == 0 {
+		return strconv.FormatInt(int64(v/day), 10) + "d"
+	}
+
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// LoadConfig reads a YAML SLO configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses a YAML SLO configuration and fills in the defaults.
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+
+	for i := range config.Objectives {
+		o := &config.Objectives[i]
+		if err := o.validate(); err != nil {
+			return nil, err
+		}
+		o.setDefaults()
+	}
+	return config, nil
+}
+
+func (o *Objective) validate() error {
+	switch {
+	case o.Name == "":
+		return errors.New("slo: objective without a name")
+	case len(o.Checks) == 0:
+		return fmt.Errorf("slo: objective %s has no checks", o.Name)
+	case o.Target <= 0 || o.Target >= 100:
+		return fmt.Errorf("slo: objective %s: target must be between 0 and 100", o.Name)
+	}
+	for _, a := range o.BurnAlerts {
+		if a.Long <= 0 || a.Short <= 0 || a.Factor <= 0 {
+			return fmt.Errorf("slo: objective %s: burn alerts need a long and short window and a factor", o.Name)
+		}
+	}
+	return nil
+}
+
+func (o *Objective) setDefaults() {
+	if o.Window == 0 {
+		o.Window = Duration(30 * 24 * time.Hour)
+	}
+	if len(o.BurnAlerts) == 0 {
+		o.BurnAlerts = DefaultBurnAlerts
+	}
+}
+
+// covers reports whether key, a check id or name, belongs to the objective.
+func (o *Objective) covers(id, name string) bool {
+	for _, c := range o.Checks {
+		if c == id || c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// budget returns the error budget as a fraction of time, like 0.001 for
+// 99.9%.
+func (o *Objective) budget() float64 {
+	return 1 - o.Target/100
+}
@@ -0,0 +1,91 @@
+package slo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type recorder struct {
+	hooks []observery.Webhook
+}
+
+func (r *recorder) Notify(ctx context.Context, hook *observery.Webhook) error {
+	r.hooks = append(r.hooks, *hook)
+	return nil
+}
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte("objectives:\n  - name: checkout\n    checks: [api, payments]\n    target: 99.9\n    window: 30d\n"))
+	if err != nil {
+		t.Fatalf("Error parsing config: %s\n", err)
+	}
+	o := c.Objectives[0]
+	if time.Duration(o.Window) != 30*24*time.Hour || len(o.BurnAlerts) != 2 || o.Window.String() != "30d" {
+		t.Fatalf("Unexpected objective: %+v\n", o)
+	}
+
+	if _, err := ParseConfig([]byte("objectives:\n  - name: x\n    checks: [a]\n    target: 100\n")); err == nil {
+		t.Fatal("Expected an error for a target of 100%")
+	}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	o := Objective{Name: "checkout", Checks: []string{"api", "2"}, Target: 99.9, Window: Duration(30 * 24 * time.Hour)}
+	outages := []observery.Outage{
+		// Overlapping outages of the group only count once.
+		{CheckName: "api", Start: now.Add(-48 * time.Hour), Stop: now.Add(-47 * time.Hour)},
+		{CheckID: "2", Start: now.Add(-47*time.Hour - 30*time.Minute), Stop: now.Add(-46*time.Hour - 30*time.Minute)},
+		// Outside the window.
+		{CheckName: "api", Start: now.Add(-40 * 24 * time.Hour), Stop: now.Add(-39 * 24 * time.Hour)},
+		// Not part of the objective.
+		{CheckName: "other", Start: now.Add(-time.Hour), Stop: now},
+		{CheckName: "api", Ongoing: true, Start: now.Add(-6 * time.Minute)},
+	}
+
+	b := Compute(o, outages, now)
+	if b.Spent != 96*time.Minute {
+		t.Fatalf("Expected 96m of downtime but got %s\n", b.Spent)
+	}
+	if b.Allowed != 43*time.Minute+12*time.Second || !b.Exhausted() {
+		t.Fatalf("Unexpected budget: %+v\n", b)
+	}
+
+	if rate := BurnRate(o, outages, time.Hour, now); rate < 99.9 || rate > 100.1 {
+		t.Fatalf("Expected a 1h burn rate of 100 but got %f\n", rate)
+	}
+}
+
+func TestAlerter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := &recorder{}
+	a := NewAlerter([]Objective{{Name: "checkout", Checks: []string{"api"}, Target: 99.9}}, rec)
+	a.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	a.Observe(ctx, &observery.Webhook{CheckID: "1", CheckName: "api", State: "down"})
+	if len(rec.hooks) != 0 {
+		t.Fatalf("Expected no alert right away but got %+v\n", rec.hooks)
+	}
+
+	// 14.4x over 1h needs about 52s of downtime, which the 5m window sees
+	// too.
+	now = now.Add(time.Minute)
+	a.Evaluate(ctx)
+	if len(rec.hooks) != 1 || rec.hooks[0].State != "burning" || rec.hooks[0].CheckName != "checkout" {
+		t.Fatalf("Expected a burning notification but got %+v\n", rec.hooks)
+	}
+	if alerts := a.Alerts(); len(alerts) != 1 || alerts[0].BurnAlert.Factor != 14.4 {
+		t.Fatalf("Expected the 1h alert to fire but got %+v\n", alerts)
+	}
+
+	a.Observe(ctx, &observery.Webhook{CheckID: "1", CheckName: "api", State: "up"})
+	now = now.Add(10 * time.Minute)
+	a.Evaluate(ctx)
+	if len(rec.hooks) != 2 || rec.hooks[1].State != "burn-resolved" {
+		t.Fatalf("Expected the short window to resolve the alert but got %+v\n", rec.hooks)
+	}
+}
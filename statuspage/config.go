@@ -0,0 +1,104 @@
+package statuspage
+
+import (
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config controls which checks are shown on the status page and how. It is
+// read from YAML like the other configuration files:
+//
+//	title: Example status
+//	days: 90
+//	groups:
+//	  - name: Core
+//	    checks:
+//	      - check: api
+//	        displayName: Public API
+//	hidden: [internal]
+//
+// Since YAML is a superset of JSON, JSON config files keep working.
+type Config struct {
+	// Title is shown at the top of the page and in the feed.
+	Title string `yaml:"title"`
+
+	// URL is the public address of the page. It's used for links in the
+	// feed.
+	URL string `yaml:"url"`
+
+	// Days is the number of days shown in the uptime bars. Defaults to 90.
+	Days int `yaml:"days"`
+
+	// IncidentDays is how far back incidents are listed. Defaults to 14.
+	IncidentDays int `yaml:"incidentDays"`
+
+	// Groups lists the checks to show, in order. Checks are matched by id
+	// or name.
+	Groups []Group `yaml:"groups"`
+
+	// Hidden lists ids or names of checks that must never be shown.
+	Hidden []string `yaml:"hidden"`
+
+	// HideUngrouped hides every check that isn't part of a group. When
+	// false they are shown in a group called "Other", or in a group per
+	// account for checks listed through an observery.MultiClient.
+	HideUngrouped bool `yaml:"hideUngrouped"`
+}
+
+// Group is a named list of checks.
+type Group struct {
+	// Name of the group.
+	Name string `yaml:"name"`
+
+	// Checks in this group.
+	Checks []CheckConfig `yaml:"checks"`
+}
+
+// CheckConfig selects a check and optionally renames it.
+type CheckConfig struct {
+	// Check is the id or name of the check.
+	Check string `yaml:"check"`
+
+	// DisplayName replaces the check name on the page when set.
+	DisplayName string `yaml:"displayName"`
+}
+
+// LoadConfig reads a YAML config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses a YAML config.
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) days() int {
+	if c.Days > 0 {
+		return c.Days
+	}
+	return 90
+}
+
+func (c *Config) incidentDays() int {
+	if c.IncidentDays > 0 {
+		return c.IncidentDays
+	}
+	return 14
+}
+
+func (c *Config) title() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return "Status"
+}
@@ -0,0 +1,187 @@
+package statuspage
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sfreiberg/observery/internal/atomicfile"
+)
+
+// WriteHTML renders page as a self-contained HTML document.
+func WriteHTML(w io.Writer, page *Page) error {
+	return htmlTemplate.Execute(w, page)
+}
+
+// WriteJSON writes page as indented JSON.
+func WriteJSON(w io.Writer, page *Page) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(page)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Link    *atomLink `xml:"link,omitempty"`
+	Updated string    `xml:"updated"`
+	Summary string    `xml:"summary"`
+}
+
+// WriteAtom writes the incidents of page as an Atom feed.
+func WriteAtom(w io.Writer, page *Page) error {
+	feed := atomFeed{
+		Title:   page.Title + " incidents",
+		ID:      "urn:observery:statuspage:" + page.Title,
+		Updated: page.Generated.UTC().Format(time.RFC3339),
+	}
+	if page.URL != "" {
+		feed.ID = page.URL
+		feed.Link = &atomLink{Href: page.URL}
+	}
+
+	for _, i := range page.Incidents {
+		entry := atomEntry{
+			ID:      "urn:observery:outage:" + i.ID,
+			Updated: i.Start.UTC().Format(time.RFC3339),
+			Link:    feed.Link,
+		}
+		if i.Ongoing {
+			entry.Title = i.Check + " is down"
+			entry.Summary = fmt.Sprintf("%s has been down since %s.", i.Check, i.Start.UTC().Format(time.RFC1123))
+		} else {
+			entry.Title = i.Check + " was down"
+			entry.Updated = i.Stop.UTC().Format(time.RFC3339)
+			entry.Summary = fmt.Sprintf("%s was down for %s starting %s.", i.Check, i.Duration, i.Start.UTC().Format(time.RFC1123))
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(feed)
+}
+
+// WriteDir renders page into dir as index.html, status.json and feed.atom.
+func WriteDir(dir string, page *Page) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name  string
+		write func(io.Writer, *Page) error
+	}{
+		{"index.html", WriteHTML},
+		{"status.json", WriteJSON},
+		{"feed.atom", WriteAtom},
+	}
+	for _, f := range files {
+		if err := writeFile(filepath.Join(dir, f.name), page, f.write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile replaces path atomically so a web server never serves a half
+// written page.
+func writeFile(path string, page *Page, write func(io.Writer, *Page) error) error {
+	return atomicfile.Write(path, 0644, func(w io.Writer) error {
+		return write(w, page)
+	})
+}
+
+var htmlTemplate = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.2f%%", f)
+	},
+	"barClass": func(f float64) string {
+		switch {
+		case f >= 99.9:
+			return "good"
+		case f >= 99:
+			return "warn"
+		}
+		return "bad"
+	},
+	"date": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/atom+xml" href="feed.atom" title="{{.Title}} incidents">
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+.banner { padding: 1em; border-radius: 4px; color: #fff; font-weight: bold; }
+.banner.up { background: #2e9d4f; }
+.banner.down { background: #c9302c; }
+.banner.unknown { background: #777; margin-top: .5em; }
+.check { margin: 1em 0; }
+.check .name { font-weight: bold; }
+.check .state { float: right; text-transform: capitalize; }
+.state.up { color: #2e9d4f; }
+.state.down { color: #c9302c; }
+.state.waiting { color: #888; }
+.bars { display: flex; height: 2em; margin: .3em 0; }
+.bars span { flex: 1; margin-right: 1px; border-radius: 1px; }
+.bars .good { background: #2e9d4f; }
+.bars .warn { background: #e6a23c; }
+.bars .bad { background: #c9302c; }
+.uptime { color: #666; font-size: .9em; }
+.incident { border-left: 3px solid #c9302c; padding-left: .8em; margin: 1em 0; }
+footer { color: #888; font-size: .8em; margin-top: 3em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if eq .State "up"}}<div class="banner up">All systems operational</div>{{else}}<div class="banner down">Some systems are down</div>{{end}}
+{{if .Unavailable}}<div class="banner unknown">Status currently unavailable for{{range $i, $a := .Unavailable}}{{if $i}},{{end}} {{$a}}{{end}}</div>{{end}}
+{{range .Groups}}
+<h2>{{.Name}}</h2>
+{{range .Checks}}
+<div class="check">
+<span class="name">{{.Name}}</span><span class="state {{.State}}">{{.State}}</span>
+<div class="bars">{{range .Days}}<span class="{{barClass .Uptime}}" title="{{.Date}}: {{percent .Uptime}}"></span>{{end}}</div>
+<div class="uptime">{{percent .Uptime}} uptime</div>
+</div>
+{{end}}
+{{end}}
+<h2>Recent incidents</h2>
+{{range .Incidents}}
+<div class="incident">
+<strong>{{.Check}}</strong>
+{{if .Ongoing}}<div>Ongoing since {{date .Start}}</div>{{else}}<div>{{date .Start}} for {{.Duration}}</div>{{end}}
+</div>
+{{else}}
+<p>No incidents reported.</p>
+{{end}}
+<footer>Updated {{date .Generated}}</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,403 @@
+// Package statuspage renders a static public status page from observery
+// checks and outages. The page is available as HTML, JSON and an Atom feed
+// of incidents.
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/store"
+)
+
+// Lister is the part of observery.Client used to fetch a Snapshot.
+type Lister interface {
+	ListChecks(ctx context.Context) (*observery.ListChecksResponse, error)
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+}
+
+// accountLister is implemented by observery.MultiClient. Fetch uses it to
+// tell which accounts are missing from a Snapshot.
+type accountLister interface {
+	FetchChecks(ctx context.Context) ([]observery.Check, error)
+	FetchOutages(ctx context.Context) ([]observery.Outage, error)
+}
+
+// Snapshot holds everything needed to render a page. It can be saved as
+// JSON and rendered later without access to the API.
+type Snapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time
+
+	// Checks holds all checks.
+	Checks []observery.Check
+
+	// Outages holds the known outages.
+	Outages []observery.Outage
+
+	// Unavailable lists the accounts of an observery.MultiClient that
+	// couldn't be fetched.
+	Unavailable []string `json:",omitempty"`
+}
+
+// Fetch takes a Snapshot of the current checks and outages. With an
+// observery.MultiClient the accounts that fail are listed in
+// Snapshot.Unavailable, and Fetch only fails if every account fails.
+func Fetch(ctx context.Context, client Lister) (*Snapshot, error) {
+	if la, ok := client.(accountLister); ok {
+		return fetchAccounts(ctx, la)
+	}
+
+	checks, err := client.ListChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !checks.Success {
+		return nil, errors.New(checks.Reason)
+	}
+
+	outages, err := client.ListOutages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !outages.Success {
+		return nil, errors.New(outages.Reason)
+	}
+
+	return &Snapshot{
+		Time:    time.Now(),
+		Checks:  checks.Checks,
+		Outages: outages.Outages,
+	}, nil
+}
+
+// AddHistory adds the outages of history that fall within the days shown
+// by config to the snapshot. The API only returns the 100 most recent
+// outages, so without a history the uptime bars undercount over longer
+// periods. Outages in the snapshot take precedence over the history,
+// which may lag behind.
+func (s *Snapshot) AddHistory(history store.Store, config *Config) error {
+	days := config.days()
+	if d := config.incidentDays(); d > days {
+		days = d
+	}
+	from := s.Time.UTC().AddDate(0, 0, -days)
+
+	outages, err := history.Range(from, s.Time)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, o := range s.Outages {
+		known[o.ID] = true
+	}
+	for _, o := range outages {
+		if !known[o.ID] {
+			s.Outages = append(s.Outages, o)
+		}
+	}
+	return nil
+}
+
+func fetchAccounts(ctx context.Context, client accountLister) (*Snapshot, error) {
+	snap := &Snapshot{Time: time.Now()}
+	unavailable := map[string]bool{}
+
+	var err error
+	if snap.Checks, err = client.FetchChecks(ctx); err != nil {
+		var failed observery.MultiError
+		if !errors.As(err, &failed) || len(snap.Checks) == 0 {
+			return nil, err
+		}
+		for _, f := range failed {
+			unavailable[f.Account] = true
+		}
+	}
+	if snap.Outages, err = client.FetchOutages(ctx); err != nil {
+		var failed observery.MultiError
+		if !errors.As(err, &failed) {
+			return nil, err
+		}
+		for _, f := range failed {
+			unavailable[f.Account] = true
+		}
+	}
+
+	for a := range unavailable {
+		snap.Unavailable = append(snap.Unavailable, a)
+	}
+	sort.Strings(snap.Unavailable)
+	return snap, nil
+}
+
+// Page is the data rendered on the status page. It is also what's written
+// as JSON.
+type Page struct {
+	// Title of the page.
+	Title string `json:"title"`
+
+	// URL is the public address of the page, if configured.
+	URL string `json:"url,omitempty"`
+
+	// Generated is when the underlying snapshot was taken.
+	Generated time.Time `json:"generated"`
+
+	// State is "down" if any visible check is down and "up" otherwise.
+	State string `json:"state"`
+
+	// Groups holds the visible checks.
+	Groups []Section `json:"groups"`
+
+	// Incidents are the recent outages of visible checks, newest first.
+	Incidents []Incident `json:"incidents"`
+
+	// Unavailable lists the accounts whose checks couldn't be fetched.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// Section is a group of checks on the page.
+type Section struct {
+	// Name of the group.
+	Name string `json:"name"`
+
+	// Checks in the group.
+	Checks []Entry `json:"checks"`
+}
+
+// Entry is a single check on the page.
+type Entry struct {
+	// ID of the check.
+	ID string `json:"id"`
+
+	// Name is the display name of the check.
+	Name string `json:"name"`
+
+	// State is the current state of the check.
+	State string `json:"state"`
+
+	// Since holds the time of the last state change.
+	Since time.Time `json:"since"`
+
+	// Uptime is the percentage of time the check was up over all Days.
+	Uptime float64 `json:"uptime"`
+
+	// Days holds the uptime per day, oldest first.
+	Days []Day `json:"days"`
+
+	// Account the check belongs to when the snapshot spans several
+	// accounts.
+	Account string `json:"account,omitempty"`
+}
+
+// Day is one bar of the uptime history.
+type Day struct {
+	// Date in YYYY-MM-DD format, in UTC.
+	Date string `json:"date"`
+
+	// Uptime is the percentage of the day the check was up.
+	Uptime float64 `json:"uptime"`
+}
+
+// Incident is an outage of a visible check.
+type Incident struct {
+	// ID of the outage.
+	ID string `json:"id"`
+
+	// Check is the display name of the check.
+	Check string `json:"check"`
+
+	// Start of the outage.
+	Start time.Time `json:"start"`
+
+	// Stop of the outage. Zero while ongoing.
+	Stop time.Time `json:"stop,omitempty"`
+
+	// Duration of the outage.
+	Duration time.Duration `json:"duration"`
+
+	// Ongoing is true while the check is still down.
+	Ongoing bool `json:"ongoing"`
+}
+
+// Build turns a Snapshot into a Page according to config.
+func Build(snap *Snapshot, config *Config) *Page {
+	page := &Page{
+		Title:     config.title(),
+		URL:       config.URL,
+		Generated: snap.Time,
+		State:     "up",
+
+		Unavailable: snap.Unavailable,
+	}
+
+	hidden := map[string]bool{}
+	for _, h := range config.Hidden {
+		hidden[h] = true
+	}
+
+	var (
+		byKey   = map[string]observery.Check{}
+		grouped = map[string]bool{}
+		names   = map[string]string{}
+	)
+	for _, c := range snap.Checks {
+		byKey[c.ID] = c
+		byKey[c.Name] = c
+	}
+
+	addSection := func(name string, checks []observery.Check) {
+		section := Section{Name: name}
+		for _, c := range checks {
+			entry := buildEntry(c, snap, config.days())
+			if n, ok := names[c.ID]; ok {
+				entry.Name = n
+			}
+			if c.State == "down" {
+				page.State = "down"
+			}
+			section.Checks = append(section.Checks, entry)
+		}
+		if len(section.Checks) > 0 {
+			page.Groups = append(page.Groups, section)
+		}
+	}
+
+	for _, g := range config.Groups {
+		var checks []observery.Check
+		for _, cc := range g.Checks {
+			c, ok := byKey[cc.Check]
+			if !ok || hidden[c.ID] || hidden[c.Name] {
+				continue
+			}
+			grouped[c.ID] = true
+			if cc.DisplayName != "" {
+				names[c.ID] = cc.DisplayName
+			}
+			checks = append(checks, c)
+		}
+		addSection(g.Name, checks)
+	}
+
+	if !config.HideUngrouped {
+		// Checks of a MultiClient get a section per account, in the order
+		// the accounts were listed.
+		var (
+			accounts []string
+			other    = map[string][]observery.Check{}
+		)
+		for _, c := range snap.Checks {
+			if !grouped[c.ID] && !hidden[c.ID] && !hidden[c.Name] {
+				grouped[c.ID] = true
+				if _, ok := other[c.Account]; !ok {
+					accounts = append(accounts, c.Account)
+				}
+				other[c.Account] = append(other[c.Account], c)
+			}
+		}
+		for _, a := range accounts {
+			if a != "" {
+				addSection(a, other[a])
+			}
+		}
+		addSection("Other", other[""])
+	}
+
+	since := snap.Time.AddDate(0, 0, -config.incidentDays())
+	for _, o := range snap.Outages {
+		if !grouped[o.CheckID] || (!o.Ongoing && o.Stop.Before(since)) {
+			continue
+		}
+		name := o.CheckName
+		if n, ok := names[o.CheckID]; ok {
+			name = n
+		}
+		page.Incidents = append(page.Incidents, Incident{
+			ID:       o.ID,
+			Check:    name,
+			Start:    o.Start,
+			Stop:     o.Stop,
+			Duration: o.Duration,
+			Ongoing:  o.Ongoing,
+		})
+	}
+	sort.Slice(page.Incidents, func(i, j int) bool {
+		return page.Incidents[i].Start.After(page.Incidents[j].Start)
+	})
+
+	return page
+}
+
+// buildEntry computes the daily uptime of check over the last days days.
+func buildEntry(check observery.Check, snap *Snapshot, days int) Entry {
+	entry := Entry{
+		ID:    check.ID,
+		Name:  check.Name,
+		State: check.State,
+		Since: check.Since,
+
+		Account: check.Account,
+	}
+
+	var (
+		now       = snap.Time.UTC()
+		today     = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		first     = today.AddDate(0, 0, 1-days)
+		total     time.Duration
+		downTotal time.Duration
+	)
+
+	for i := 0; i < days; i++ {
+		start := first.AddDate(0, 0, i)
+		end := start.AddDate(0, 0, 1)
+		if end.After(now) {
+			end = now
+		}
+
+		var down time.Duration
+		for _, o := range snap.Outages {
+			if o.CheckID == check.ID {
+				down += overlap(o, start, end, now)
+			}
+		}
+
+		length := end.Sub(start)
+		total += length
+		downTotal += down
+		entry.Days = append(entry.Days, Day{
+			Date:   start.Format("2006-01-02"),
+			Uptime: uptime(length, down),
+		})
+	}
+	entry.Uptime = uptime(total, downTotal)
+
+	return entry
+}
+
+// overlap returns how much of the outage falls between start and end.
+func overlap(o observery.Outage, start, end, now time.Time) time.Duration {
+	stop := o.Stop
+	if o.Ongoing || stop.IsZero() {
+		stop = now
+	}
+	if o.Start.After(start) {
+		start = o.Start
+	}
+	if stop.Before(end) {
+		end = stop
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+func uptime(total, down time.Duration) float64 {
+	if total <= 0 {
+		return 100
+	}
+	return 100 * float64(total-down) / float64(total)
+}
@@ -0,0 +1,204 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/store"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2019, 10, 27, 12, 0, 0, 0, time.UTC)
+	snap := &Snapshot{
+		Time: now,
+		Checks: []observery.Check{
+			{ID: "1", Name: "api", State: "up"},
+			{ID: "2", Name: "db", State: "down"},
+			{ID: "3", Name: "internal", State: "up"},
+			{ID: "4", Name: "misc", State: "up"},
+		},
+		Outages: []observery.Outage{
+			{ID: "o1", CheckID: "1", CheckName: "api", Start: now.Add(-36 * time.Hour), Stop: now.Add(-30 * time.Hour), Duration: 6 * time.Hour},
+			{ID: "o2", CheckID: "2", CheckName: "db", Start: now.Add(-time.Hour), Ongoing: true},
+			{ID: "o3", CheckID: "3", CheckName: "internal", Start: now.Add(-time.Hour), Ongoing: true},
+		},
+	}
+	config := &Config{
+		Title: "Example",
+		Days:  3,
+		Groups: []Group{
+			{Name: "Core", Checks: []CheckConfig{{Check: "1", DisplayName: "Public API"}, {Check: "db"}}},
+		},
+		Hidden: []string{"internal"},
+	}
+
+	page := Build(snap, config)
+	if page.State != "down" {
+		t.Fatalf("Expected the page to be down but got %s\n", page.State)
+	}
+	if len(page.Groups) != 2 || page.Groups[1].Name != "Other" || len(page.Groups[1].Checks) != 1 {
+		t.Fatalf("Expected Core and Other groups but got %+v\n", page.Groups)
+	}
+
+	api := page.Groups[0].Checks[0]
+	if api.Name != "Public API" {
+		t.Fatalf("Expected the display name to be used but got %s\n", api.Name)
+	}
+	if len(api.Days) != 3 || api.Days[1].Date != "2019-10-26" || api.Days[1].Uptime != 75 {
+		t.Fatalf("Expected 75%% uptime on 2019-10-26 but got %+v\n", api.Days)
+	}
+
+	if len(page.Incidents) != 2 || page.Incidents[0].ID != "o2" || page.Incidents[1].Check != "Public API" {
+		t.Fatalf("Expected two visible incidents but got %+v\n", page.Incidents)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, page); err != nil {
+		t.Fatalf("Error rendering html: %s\n", err)
+	}
+	if strings.Contains(buf.String(), "internal") {
+		t.Fatal("Expected hidden checks to be left out of the html")
+	}
+
+	buf.Reset()
+	if err := WriteAtom(&buf, page); err != nil {
+		t.Fatalf("Error rendering feed: %s\n", err)
+	}
+	if !strings.Contains(buf.String(), "<title>db is down</title>") {
+		t.Fatalf("Expected an entry for the ongoing outage but got:\n%s\n", buf.String())
+	}
+}
+
+func TestBuildAccounts(t *testing.T) {
+	snap := &Snapshot{
+		Time: time.Date(2019, 10, 27, 12, 0, 0, 0, time.UTC),
+		Checks: []observery.Check{
+			{ID: "prod/1", Name: "api", State: "up", Account: "prod"},
+			{ID: "staging/1", Name: "api", State: "up", Account: "staging"},
+			{ID: "prod/2", Name: "db", State: "up", Account: "prod"},
+		},
+	}
+
+	page := Build(snap, &Config{})
+	if len(page.Groups) != 2 || page.Groups[0].Name != "prod" || len(page.Groups[0].Checks) != 2 || page.Groups[1].Name != "staging" {
+		t.Fatalf("Expected a group per account but got %+v\n", page.Groups)
+	}
+	if page.Groups[1].Checks[0].Account != "staging" {
+		t.Fatalf("Expected the account on the entry but got %+v\n", page.Groups[1].Checks[0])
+	}
+}
+
+// brokenStaging fakes the observery API for a MultiClient, failing every
+// request made with the "staging" username.
+type brokenStaging struct{}
+
+func (brokenStaging) RoundTrip(req *http.Request) (*http.Response, error) {
+	if account, _, _ := req.BasicAuth(); account == "staging" {
+		return nil, errors.New("connection refused")
+	}
+
+	body := `{"success": true, "result": []}`
+	if strings.HasSuffix(req.URL.Path, "/check") {
+		body = `{"success": true, "result": [{"id": "1", "name": "api", "type": "http", "state": "up"}]}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestFetchAccounts(t *testing.T) {
+	defer func(rt http.RoundTripper) { http.DefaultTransport = rt }(http.DefaultTransport)
+	http.DefaultTransport = brokenStaging{}
+
+	client, err := observery.NewMultiClient(map[string]*observery.Client{
+		"prod":    observery.NewClient("prod", "secret"),
+		"staging": observery.NewClient("staging", "secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := Fetch(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Expected a partial snapshot but got %s\n", err)
+	}
+	if len(snap.Checks) != 1 || len(snap.Unavailable) != 1 || snap.Unavailable[0] != "staging" {
+		t.Fatalf("Expected staging to be unavailable but got %+v\n", snap)
+	}
+
+	page := Build(snap, &Config{})
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, page); err != nil {
+		t.Fatalf("Error rendering html: %s\n", err)
+	}
+	if !strings.Contains(buf.String(), "Status currently unavailable for staging") {
+		t.Fatalf("Expected the unavailable account on the page:\n%s\n", buf.String())
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte("title: Example\ngroups:\n  - name: Core\n    checks:\n      - check: api\n        displayName: Public API\nhidden: [internal]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Title != "Example" || len(config.Groups) != 1 || config.Groups[0].Checks[0].DisplayName != "Public API" || config.Hidden[0] != "internal" {
+		t.Fatalf("Unexpected config %+v\n", config)
+	}
+
+	// JSON configs still work.
+	if config, err = ParseConfig([]byte(`{"title": "Example", "hideUngrouped": true}`)); err != nil || !config.HideUngrouped {
+		t.Fatalf("Expected the JSON config to parse but got %+v: %v\n", config, err)
+	}
+
+	if _, err := ParseConfig([]byte("titel: Example\n")); err == nil {
+		t.Fatal("Expected unknown fields to be rejected")
+	}
+}
+
+func TestAddHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statuspage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	history, err := store.Open(filepath.Join(dir, "outages.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 10, 27, 12, 0, 0, 0, time.UTC)
+	history.Upsert(
+		observery.Outage{ID: "old", CheckID: "1", Start: now.AddDate(0, 0, -60), Stop: now.AddDate(0, 0, -60).Add(12 * time.Hour), Duration: 12 * time.Hour},
+		observery.Outage{ID: "gone", CheckID: "1", Start: now.AddDate(0, 0, -200), Stop: now.AddDate(0, 0, -200).Add(time.Hour), Duration: time.Hour},
+		observery.Outage{ID: "o1", CheckID: "1", Start: now.Add(-2 * time.Hour), Ongoing: true},
+	)
+
+	snap := &Snapshot{
+		Time:    now,
+		Checks:  []observery.Check{{ID: "1", Name: "api", State: "up"}},
+		Outages: []observery.Outage{{ID: "o1", CheckID: "1", Start: now.Add(-2 * time.Hour), Stop: now.Add(-time.Hour), Duration: time.Hour}},
+	}
+	if err := snap.AddHistory(history, &Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Outages) != 2 || snap.Outages[0].Ongoing || snap.Outages[1].ID != "old" {
+		t.Fatalf("Expected the API outage and the old one from the history but got %+v\n", snap.Outages)
+	}
+
+	page := Build(snap, &Config{})
+	if api := page.Groups[0].Checks[0]; api.Uptime > 99.9 {
+		t.Fatalf("Expected the old outage to count against the uptime but got %v\n", api.Uptime)
+	}
+}
@@ -0,0 +1,217 @@
+// Package store keeps a local history of observery outages. The API only
+// returns the 100 most recent outages, so syncing them into a Store
+// periodically preserves the full history for offline queries, SLA
+// calculations and exports.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/internal/atomicfile"
+)
+
+// Store holds outages keyed by their id.
+type Store interface {
+	// Upsert adds the outages, replacing any stored outage with the same
+	// id.
+	Upsert(outages ...observery.Outage) error
+
+	// Get returns the outage with the given id. The bool is false if the
+	// outage isn't stored.
+	Get(id string) (observery.Outage, bool, error)
+
+	// Range returns the outages that overlap the time between from and
+	// to, oldest first. A zero from or to leaves that side unbounded.
+	// Ongoing outages overlap everything after their start.
+	Range(from, to time.Time) ([]observery.Outage, error)
+}
+
+// FileStore is a Store that keeps all outages in memory and saves them to
+// a JSON file after every change.
+type FileStore struct {
+	path string
+
+	mu      sync.RWMutex
+	outages map[string]observery.Outage
+}
+
+// Open loads the FileStore at path. The file is created on the first
+// Upsert if it doesn't exist.
+func Open(path string) (*FileStore, error) {
+	s := &FileStore{path: path, outages: map[string]observery.Outage{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var outages []observery.Outage
+	if err := json.Unmarshal(data, &outages); err != nil {
+		return nil, err
+	}
+	for _, o := range outages {
+		s.outages[o.ID] = o
+	}
+	return s, nil
+}
+
+// Upsert adds or replaces the outages and saves the file.
+func (s *FileStore) Upsert(outages ...observery.Outage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range outages {
+		s.outages[o.ID] = o
+	}
+	return s.save()
+}
+
+// Get returns the outage with the given id.
+func (s *FileStore) Get(id string) (observery.Outage, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.outages[id]
+	return o, ok, nil
+}
+
+// Range returns the outages overlapping from and to, oldest first.
+func (s *FileStore) Range(from, to time.Time) ([]observery.Outage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	outages := make([]observery.Outage, 0, len(s.outages))
+	for _, o := range s.outages {
+		outages = append(outages, o)
+	}
+	return Filter(outages, from, to), nil
+}
+
+// save atomically replaces the file with all outages.
+// Must be called with s.mu held.
+func (s *FileStore) save() error {
+	outages := make([]observery.Outage, 0, len(s.outages))
+	for _, o := range s.outages {
+		outages = append(outages, o)
+	}
+	sortOutages(outages)
+
+	data, err := json.Marshal(outages)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.path, data, 0600)
+}
+
+// Filter returns the outages that overlap the time between from and to,
+// oldest first, using the same rules as Store.Range.
+func Filter(outages []observery.Outage, from, to time.Time) []observery.Outage {
+	var filtered []observery.Outage
+	for _, o := range outages {
+		if overlaps(o, from, to) {
+			filtered = append(filtered, o)
+		}
+	}
+	sortOutages(filtered)
+	return filtered
+}
+
+func overlaps(o observery.Outage, from, to time.Time) bool {
+	if !to.IsZero() && !o.Start.Before(to) {
+		return false
+	}
+	if from.IsZero() || o.Ongoing || o.Stop.IsZero() {
+		return true
+	}
+	return o.Stop.After(from)
+}
+
+func sortOutages(outages []observery.Outage) {
+	sort.Slice(outages, func(i, j int) bool {
+		if outages[i].Start.Equal(outages[j].Start) {
+			return outages[i].ID < outages[j].ID
+		}
+		return outages[i].Start.Before(outages[j].Start)
+	})
+}
+
+// Source is the part of observery.Client used to sync a Store.
+type Source interface {
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+	GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error)
+}
+
+// Sync upserts the most recent outages from the API into s. Outages stored
+// as ongoing that are no longer part of the recent outages are fetched
+// individually so they get closed.
+func Sync(ctx context.Context, s Store, src Source) error {
+	resp, err := src.ListOutages(ctx)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Reason)
+	}
+
+	seen := map[string]bool{}
+	for _, o := range resp.Outages {
+		seen[o.ID] = true
+	}
+	if err := s.Upsert(resp.Outages...); err != nil {
+		return err
+	}
+
+	stored, err := s.Range(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, o := range stored {
+		if !o.Ongoing || seen[o.ID] {
+			continue
+		}
+
+		resp, err := src.GetOutage(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			continue
+		}
+
+		o.Ongoing = resp.Outage.Ongoing
+		o.Stop = resp.Outage.Stop
+		o.Duration = resp.Outage.Duration
+		if err := s.Upsert(o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run calls Sync every interval until ctx is done. Errors are passed to
+// onError, which may be nil, and don't stop the loop.
+func Run(ctx context.Context, s Store, src Source, interval time.Duration, onError func(error)) {
+	for {
+		if err := Sync(ctx, s, src); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
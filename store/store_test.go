@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type fakeSource struct {
+	recent []observery.Outage
+	closed map[string]observery.Outage
+}
+
+func (f *fakeSource) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return &observery.ListOutagesResponse{Success: true, Outages: f.recent}, nil
+}
+
+func (f *fakeSource) GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error) {
+	resp := &observery.GetOutageResponse{Success: true}
+	o := f.closed[id]
+	resp.Outage.ID = o.ID
+	resp.Outage.Stop = o.Stop
+	resp.Outage.Duration = o.Duration
+	return resp, nil
+}
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outages.json")
+
+	day := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error opening store: %s\n", err)
+	}
+
+	src := &fakeSource{recent: []observery.Outage{
+		{ID: "a", Start: day, Stop: day.Add(time.Hour), Duration: time.Hour},
+		{ID: "b", Start: day.AddDate(0, 0, 1), Ongoing: true},
+	}}
+	if err := Sync(context.Background(), s, src); err != nil {
+		t.Fatalf("Error syncing: %s\n", err)
+	}
+
+	// b fell out of the recent outages while it was closed.
+	src.recent = []observery.Outage{{ID: "c", Start: day.AddDate(0, 0, 5), Stop: day.AddDate(0, 0, 5).Add(time.Minute)}}
+	src.closed = map[string]observery.Outage{"b": {ID: "b", Stop: day.AddDate(0, 0, 2), Duration: 24 * time.Hour}}
+	if err := Sync(context.Background(), s, src); err != nil {
+		t.Fatalf("Error syncing: %s\n", err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("Error reopening store: %s\n", err)
+	}
+
+	b, ok, _ := s.Get("b")
+	if !ok || b.Ongoing || b.Duration != 24*time.Hour {
+		t.Fatalf("Expected b to be closed but got %+v\n", b)
+	}
+
+	outages, _ := s.Range(day.Add(30*time.Minute), day.AddDate(0, 0, 3))
+	if len(outages) != 2 || outages[0].ID != "a" || outages[1].ID != "b" {
+		t.Fatalf("Expected a and b but got %+v\n", outages)
+	}
+
+	outages, _ = s.Range(time.Time{}, time.Time{})
+	if len(outages) != 3 {
+		t.Fatalf("Expected 3 outages but got %d\n", len(outages))
+	}
+}
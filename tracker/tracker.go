@@ -0,0 +1,352 @@
+// Package tracker builds a timeline of outages from observery webhooks.
+// Webhooks arrive in real time but don't carry an outage id, so the
+// Tracker opens a local outage when a check goes down and closes it when
+// the check comes back up. Reconciling with the API attaches the official
+// outage ids and repairs the timeline when webhooks were lost or arrived
+// out of order.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sfreiberg/observery"
+	"github.com/sfreiberg/observery/store"
+)
+
+// Outage is an outage on the tracked timeline.
+type Outage struct {
+	// LocalID identifies the outage until it has been reconciled.
+	LocalID string
+
+	// ID is the official observery outage id. Empty until reconciled.
+	ID string
+
+	// CheckID of the check that is down.
+	CheckID string
+
+	// CheckName of the check that is down.
+	CheckName string
+
+	// Start of the outage.
+	Start time.Time
+
+	// Stop of the outage. Zero while ongoing.
+	Stop time.Time
+
+	// Ongoing is true until the check is back up.
+	Ongoing bool
+
+	// Reconciled is true once the outage has been matched with the API.
+	Reconciled bool
+}
+
+// covers reports whether t falls within the outage.
+func (o *Outage) covers(t time.Time) bool {
+	return !t.Before(o.Start) && (o.Ongoing || !t.After(o.Stop))
+}
+
+// Source is the part of observery.Client used by Tracker.Reconcile.
+type Source interface {
+	ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error)
+	GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error)
+}
+
+// Tracker keeps the outage timeline. The zero value is ready to use.
+type Tracker struct {
+	// Tolerance is how far apart the start of a local and an official
+	// outage may be to be considered the same outage. Defaults to 5
+	// minutes.
+	Tolerance time.Duration
+
+	// Store, when set, receives every reconciled outage.
+	Store store.Store
+
+	mu      sync.Mutex
+	outages []*Outage
+	lastID  int
+
+	// orphanUps holds the time of "up" webhooks that arrived while no
+	// outage was open, in case the matching "down" arrives late.
+	orphanUps map[string]time.Time
+
+	// pruned holds the latest stop of the outages forgotten by Prune per
+	// check.
+	pruned map[string]time.Time
+}
+
+// Handler returns an http.HandlerFunc that tracks every webhook sent by
+// observery.com.
+func (t *Tracker) Handler() http.HandlerFunc {
+	return observery.WebhookHandler(func(hook *observery.Webhook, err error) {
+		if err == nil {
+			t.Observe(hook)
+		}
+	})
+}
+
+// Observe records a webhook received now.
+func (t *Tracker) Observe(hook *observery.Webhook) {
+	t.ObserveAt(hook, time.Now())
+}
+
+// ObserveAt records a webhook that was received at the given time. Only
+// "down" and "up" webhooks change the timeline.
+func (t *Tracker) ObserveAt(hook *observery.Webhook, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.orphanUps == nil {
+		t.orphanUps = map[string]time.Time{}
+	}
+
+	switch hook.State {
+	case "down":
+		t.down(hook, at)
+	case "up":
+		t.up(hook, at)
+	}
+}
+
+func (t *Tracker) down(hook *observery.Webhook, at time.Time) {
+	var (
+		open     *Outage
+		lastStop = t.pruned[hook.CheckID]
+	)
+	for _, o := range t.outages {
+		if o.CheckID != hook.CheckID {
+			continue
+		}
+		if o.covers(at) {
+			return
+		}
+		if o.Ongoing {
+			open = o
+		} else if o.Stop.After(lastStop) {
+			lastStop = o.Stop
+		}
+	}
+
+	// A "down" from before the last outage ended is stale. It must not
+	// stretch the open outage over the closed one.
+	if at.Before(lastStop) {
+		return
+	}
+
+	// An earlier "down" arrived after a later one.
+	if open != nil {
+		open.Start = at
+		return
+	}
+
+	o := t.open(hook, at)
+
+	// The "up" for this outage already arrived.
+	if up, ok := t.orphanUps[hook.CheckID]; ok && up.After(at) {
+		o.Stop = up
+		o.Ongoing = false
+		delete(t.orphanUps, hook.CheckID)
+	}
+}
+
+func (t *Tracker) up(hook *observery.Webhook, at time.Time) {
+	for _, o := range t.outages {
+		if o.CheckID != hook.CheckID || !o.Ongoing {
+			continue
+		}
+		if !at.Before(o.Start) {
+			o.Stop = at
+			o.Ongoing = false
+		}
+		// Otherwise the "up" belongs to an earlier outage and is stale.
+		return
+	}
+
+	if prev, ok := t.orphanUps[hook.CheckID]; !ok || at.After(prev) {
+		t.orphanUps[hook.CheckID] = at
+	}
+}
+
+func (t *Tracker) open(hook *observery.Webhook, at time.Time) *Outage {
+	t.lastID++
+	o := &Outage{
+		LocalID:   "local-" + strconv.Itoa(t.lastID),
+		CheckID:   hook.CheckID,
+		CheckName: hook.CheckName,
+		Start:     at,
+		Ongoing:   true,
+	}
+	t.outages = append(t.outages, o)
+	return o
+}
+
+// Outages returns a copy of the timeline, oldest first. If checkID isn't
+// empty only outages of that check are returned.
+func (t *Tracker) Outages(checkID string) []Outage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var outages []Outage
+	for _, o := range t.outages {
+		if checkID == "" || o.CheckID == checkID {
+			outages = append(outages, *o)
+		}
+	}
+	sort.Slice(outages, func(i, j int) bool {
+		return outages[i].Start.Before(outages[j].Start)
+	})
+	return outages
+}
+
+// Prune forgets the outages that stopped before t, so a long running
+// Tracker doesn't keep its whole history. Late webhooks from before a
+// forgotten outage are still ignored.
+func (t *Tracker) Prune(before time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pruned == nil {
+		t.pruned = map[string]time.Time{}
+	}
+
+	kept := t.outages[:0]
+	for _, o := range t.outages {
+		if o.Ongoing || !o.Stop.Before(before) {
+			kept = append(kept, o)
+			continue
+		}
+		if o.Stop.After(t.pruned[o.CheckID]) {
+			t.pruned[o.CheckID] = o.Stop
+		}
+	}
+	for i := len(kept); i < len(t.outages); i++ {
+		t.outages[i] = nil
+	}
+	t.outages = kept
+
+	for id, up := range t.orphanUps {
+		if up.Before(before) {
+			delete(t.orphanUps, id)
+		}
+	}
+}
+
+// Reconcile matches the timeline with the outages known to the API. Local
+// outages get the official id, start and stop. Official outages that were
+// missed are added, and reconciled outages that are still ongoing locally
+// but no longer listed are fetched individually to close them.
+func (t *Tracker) Reconcile(ctx context.Context, src Source) error {
+	resp, err := src.ListOutages(ctx)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Reason)
+	}
+
+	official := resp.Outages
+	listed := map[string]bool{}
+	for _, o := range official {
+		listed[o.ID] = true
+	}
+
+	// Fetch reconciled outages that fell out of the list while ongoing.
+	for _, o := range t.Outages("") {
+		if o.ID == "" || !o.Ongoing || listed[o.ID] {
+			continue
+		}
+		resp, err := src.GetOutage(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if resp.Success {
+			official = append(official, observery.Outage{
+				ID:        resp.Outage.ID,
+				CheckID:   resp.Outage.CheckID,
+				CheckName: resp.Outage.CheckName,
+				Ongoing:   resp.Outage.Ongoing,
+				Start:     resp.Outage.Start,
+				Stop:      resp.Outage.Stop,
+				Duration:  resp.Outage.Duration,
+			})
+		}
+	}
+
+	t.mu.Lock()
+	for _, o := range official {
+		t.merge(o)
+	}
+	t.mu.Unlock()
+
+	if t.Store != nil {
+		return t.Store.Upsert(official...)
+	}
+	return nil
+}
+
+// merge applies an official outage to the timeline. Must be called with
+// t.mu held.
+func (t *Tracker) merge(official observery.Outage) {
+	tolerance := t.Tolerance
+	if tolerance == 0 {
+		tolerance = 5 * time.Minute
+	}
+
+	// Outages forgotten by Prune stay forgotten.
+	if !official.Ongoing && !official.Stop.After(t.pruned[official.CheckID]) {
+		return
+	}
+
+	var match *Outage
+	for _, o := range t.outages {
+		if o.ID == official.ID {
+			match = o
+			break
+		}
+	}
+	if match == nil {
+		for _, o := range t.outages {
+			if o.ID != "" || o.CheckID != official.CheckID {
+				continue
+			}
+			if d := o.Start.Sub(official.Start); (d >= -tolerance && d <= tolerance) || o.covers(official.Start) {
+				match = o
+				break
+			}
+		}
+	}
+	if match == nil {
+		t.lastID++
+		match = &Outage{LocalID: "local-" + strconv.Itoa(t.lastID)}
+		t.outages = append(t.outages, match)
+	}
+
+	match.ID = official.ID
+	match.CheckID = official.CheckID
+	match.CheckName = official.CheckName
+	match.Start = official.Start
+	match.Stop = official.Stop
+	match.Ongoing = official.Ongoing
+	match.Reconciled = true
+}
+
+// Run calls Reconcile every interval until ctx is done. Errors are passed
+// to onError, which may be nil.
+func (t *Tracker) Run(ctx context.Context, src Source, interval time.Duration, onError func(error)) {
+	for {
+		if err := t.Reconcile(ctx, src); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
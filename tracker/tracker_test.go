@@ -0,0 +1,124 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sfreiberg/observery"
+)
+
+type fakeSource struct {
+	outages []observery.Outage
+}
+
+func (f *fakeSource) ListOutages(ctx context.Context) (*observery.ListOutagesResponse, error) {
+	return &observery.ListOutagesResponse{Success: true, Outages: f.outages}, nil
+}
+
+func (f *fakeSource) GetOutage(ctx context.Context, id string) (*observery.GetOutageResponse, error) {
+	return &observery.GetOutageResponse{Reason: "not found"}, nil
+}
+
+func TestTracker(t *testing.T) {
+	var (
+		tr   = &Tracker{}
+		base = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+		down = &observery.Webhook{CheckID: "a", CheckName: "api", State: "down"}
+		up   = &observery.Webhook{CheckID: "a", CheckName: "api", State: "up"}
+	)
+
+	tr.ObserveAt(down, base)
+	tr.ObserveAt(down, base.Add(time.Minute))
+	tr.ObserveAt(up, base.Add(10*time.Minute))
+
+	// The up of the second outage arrives before its down.
+	tr.ObserveAt(up, base.Add(time.Hour+5*time.Minute))
+	tr.ObserveAt(down, base.Add(time.Hour))
+
+	outages := tr.Outages("a")
+	if len(outages) != 2 {
+		t.Fatalf("Expected 2 outages but got %+v\n", outages)
+	}
+	if outages[0].Ongoing || !outages[0].Stop.Equal(base.Add(10*time.Minute)) {
+		t.Fatalf("Expected the first outage to be closed after 10 minutes but got %+v\n", outages[0])
+	}
+	if outages[1].Ongoing || !outages[1].Stop.Equal(base.Add(time.Hour+5*time.Minute)) {
+		t.Fatalf("Expected the late down to be closed by the early up but got %+v\n", outages[1])
+	}
+
+	src := &fakeSource{outages: []observery.Outage{
+		{ID: "o1", CheckID: "a", CheckName: "api", Start: base.Add(-30 * time.Second), Stop: base.Add(9 * time.Minute)},
+		{ID: "o2", CheckID: "a", CheckName: "api", Start: base.Add(time.Hour), Stop: base.Add(time.Hour + 5*time.Minute)},
+		{ID: "o3", CheckID: "b", CheckName: "db", Start: base, Ongoing: true},
+	}}
+	if err := tr.Reconcile(context.Background(), src); err != nil {
+		t.Fatalf("Error reconciling: %s\n", err)
+	}
+
+	outages = tr.Outages("")
+	if len(outages) != 3 {
+		t.Fatalf("Expected the missed outage to be added but got %+v\n", outages)
+	}
+	if outages[0].ID != "o1" || !outages[0].Start.Equal(base.Add(-30*time.Second)) || !outages[0].Reconciled {
+		t.Fatalf("Expected the first outage to be reconciled but got %+v\n", outages[0])
+	}
+	if outages[1].ID != "o3" || !outages[1].Ongoing || outages[2].ID != "o2" {
+		t.Fatalf("Unexpected timeline: %+v\n", outages)
+	}
+}
+
+func TestLateDown(t *testing.T) {
+	var (
+		tr   = &Tracker{}
+		base = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+		down = &observery.Webhook{CheckID: "a", State: "down"}
+		up   = &observery.Webhook{CheckID: "a", State: "up"}
+	)
+
+	tr.ObserveAt(down, base)
+	tr.ObserveAt(up, base.Add(10*time.Minute))
+	tr.ObserveAt(down, base.Add(time.Hour))
+
+	// A down from before the first outage arrives very late.
+	tr.ObserveAt(down, base.Add(-time.Hour))
+
+	outages := tr.Outages("a")
+	if len(outages) != 2 || !outages[0].Start.Equal(base) || !outages[1].Start.Equal(base.Add(time.Hour)) {
+		t.Fatalf("Expected the late down to be ignored but got %+v\n", outages)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var (
+		tr   = &Tracker{}
+		base = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+		down = &observery.Webhook{CheckID: "a", State: "down"}
+		up   = &observery.Webhook{CheckID: "a", State: "up"}
+	)
+
+	tr.ObserveAt(down, base)
+	tr.ObserveAt(up, base.Add(10*time.Minute))
+	tr.ObserveAt(down, base.Add(time.Hour))
+	tr.ObserveAt(up, base.Add(time.Hour+10*time.Minute))
+	tr.ObserveAt(down, base.Add(2*time.Hour))
+
+	tr.Prune(base.Add(90 * time.Minute))
+	outages := tr.Outages("")
+	if len(outages) != 1 || !outages[0].Ongoing {
+		t.Fatalf("Expected only the ongoing outage to be kept but got %+v\n", outages)
+	}
+
+	// Late webhooks and official outages from before the pruned ones
+	// don't come back.
+	tr.ObserveAt(down, base.Add(5*time.Minute))
+	src := &fakeSource{outages: []observery.Outage{
+		{ID: "o1", CheckID: "a", Start: base, Stop: base.Add(10 * time.Minute)},
+	}}
+	if err := tr.Reconcile(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+	if outages := tr.Outages(""); len(outages) != 1 || !outages[0].Start.Equal(base.Add(2*time.Hour)) {
+		t.Fatalf("Expected pruned outages to stay forgotten but got %+v\n", outages)
+	}
+}
@@ -0,0 +1,145 @@
+package observery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrFailState is wrapped by WaitError when the check reached one of
+// WaitOptions.FailStates.
+var ErrFailState = errors.New("check reached a fail state")
+
+// WaitOptions holds the optional settings used by Client.WaitForState. A nil
+// *WaitOptions uses the defaults.
+type WaitOptions struct {
+	// Interval is how often the check is polled. Defaults to 30 seconds.
+	Interval time.Duration
+
+	// Timeout is how long to wait before giving up. Zero means wait until
+	// the context is done.
+	Timeout time.Duration
+
+	// Consecutive is the number of polls in a row that have to report the
+	// wanted state. Defaults to 1.
+	Consecutive int
+
+	// FailStates are states that stop the wait immediately, for example
+	// "down" while waiting for "up".
+	FailStates []string
+}
+
+// WaitError is returned by Client.WaitForState when it gives up. It holds
+// what was last seen so the caller can report why.
+type WaitError struct {
+	// CheckID is the id of the check that was waited on.
+	CheckID string
+
+	// State is the last observed state of the check. Empty if the check was
+	// never successfully fetched.
+	State string
+
+	// OutageID is the last observed outage id, if the check was down.
+	OutageID string
+
+	// Err is the underlying reason: ErrFailState or the context error when
+	// the timeout expired or ctx was cancelled.
+	Err error
+
+	// LastErr is the error of the last poll, if it failed. It usually
+	// explains why the timeout expired.
+	LastErr error
+}
+
+func (e *WaitError) Error() string {
+	msg := fmt.Sprintf("observery: gave up waiting on check %s", e.CheckID)
+	if e.State != "" {
+		msg += fmt.Sprintf(", last state %q", e.State)
+	}
+	if e.OutageID != "" {
+		msg += fmt.Sprintf(", outage %s", e.OutageID)
+	}
+	msg += ": " + e.Err.Error()
+	if e.LastErr != nil {
+		msg += fmt.Sprintf(" (last error: %s)", e.LastErr)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// WaitForState polls Client.GetCheck until the check reports state for
+// opts.Consecutive polls in a row. It returns a *WaitError if the check
+// reaches one of opts.FailStates, the timeout expires or ctx is done.
+func (c *Client) WaitForState(ctx context.Context, checkID, state string, opts *WaitOptions) error {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+
+	interval := opts.Interval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+
+	consecutive := opts.Consecutive
+	if consecutive < 1 {
+		consecutive = 1
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var (
+		werr    = &WaitError{CheckID: checkID}
+		matches = 0
+	)
+
+	for {
+		resp, err := c.GetCheck(ctx, checkID)
+		if err == nil && !resp.Success {
+			err = errors.New(resp.Reason)
+		}
+
+		if err != nil {
+			werr.LastErr = err
+			matches = 0
+		} else {
+			werr.LastErr = nil
+			werr.State = resp.Check.State
+			werr.OutageID = ""
+			if resp.Check.OutageID != nil {
+				werr.OutageID = *resp.Check.OutageID
+			}
+
+			for _, fail := range opts.FailStates {
+				if resp.Check.State == fail {
+					werr.Err = ErrFailState
+					return werr
+				}
+			}
+
+			if resp.Check.State == state {
+				matches++
+				if matches >= consecutive {
+					return nil
+				}
+			} else {
+				matches = 0
+			}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			werr.Err = ctx.Err()
+			return werr
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package observery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkBody(state, outageID string) string {
+	outage := "null"
+	if outageID != "" {
+		outage = `"` + outageID + `"`
+	}
+	return `{"success": true, "result": {"id": "1", "state": "` + state + `", "outageId": ` + outage + `}}`
+}
+
+func TestWaitForState(t *testing.T) {
+	api := &fakeAPI{sequence: []string{
+		checkBody("down", "9"),
+		"error",
+		checkBody("up", ""),
+		checkBody("down", "10"),
+		checkBody("up", ""),
+	}}
+
+	opts := &WaitOptions{Interval: time.Millisecond, Consecutive: 2}
+	if err := fakeClient(api).WaitForState(context.Background(), "1", "up", opts); err != nil {
+		t.Fatalf("Expected the check to come up but got %s\n", err)
+	}
+	if len(api.requests) != 6 {
+		t.Fatalf("Expected 6 polls for 2 consecutive matches but got %d\n", len(api.requests))
+	}
+}
+
+func TestWaitForStateFailState(t *testing.T) {
+	api := &fakeAPI{sequence: []string{checkBody("waiting", ""), checkBody("down", "9")}}
+
+	err := fakeClient(api).WaitForState(context.Background(), "1", "up", &WaitOptions{Interval: time.Millisecond, FailStates: []string{"down"}})
+	var werr *WaitError
+	if !errors.As(err, &werr) || !errors.Is(err, ErrFailState) || werr.State != "down" || werr.OutageID != "9" {
+		t.Fatalf("Expected a fail state error with outage 9 but got %v\n", err)
+	}
+}
+
+func TestWaitForStateTimeout(t *testing.T) {
+	api := &fakeAPI{sequence: []string{checkBody("down", "9")}}
+
+	// The first poll happens right away, the second never.
+	err := fakeClient(api).WaitForState(context.Background(), "1", "up", &WaitOptions{Interval: time.Hour, Timeout: 100 * time.Millisecond})
+	var werr *WaitError
+	if !errors.As(err, &werr) || !errors.Is(err, context.DeadlineExceeded) || werr.State != "down" || werr.LastErr != nil {
+		t.Fatalf("Expected a timeout in state down but got %v\n", err)
+	}
+	if !strings.Contains(err.Error(), `last state "down", outage 9`) {
+		t.Fatalf("Expected the last state in the message but got %q\n", err)
+	}
+}
+
+func TestWaitForStateAPIError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Give up after the third poll, which fails like the second.
+	api := &fakeAPI{sequence: []string{checkBody("down", ""), "error"}}
+	api.after = func(n int) {
+		if n == 3 {
+			cancel()
+		}
+	}
+
+	err := fakeClient(api).WaitForState(ctx, "1", "up", &WaitOptions{Interval: time.Millisecond})
+	var werr *WaitError
+	if !errors.As(err, &werr) || !errors.Is(err, context.Canceled) || werr.State != "down" || werr.LastErr == nil {
+		t.Fatalf("Expected to give up with the last API error but got %v\n", err)
+	}
+	if !strings.Contains(err.Error(), "connection refused") || len(api.requests) != 3 {
+		t.Fatalf("Expected the API error after 3 polls in the message but got %q\n", err)
+	}
+}
@@ -0,0 +1,287 @@
+package observery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sfreiberg/observery/internal/atomicfile"
+)
+
+// WatchOptions holds the optional settings used by Client.WatchChecks. A nil
+// *WatchOptions uses the defaults.
+type WatchOptions struct {
+	// Jitter is the maximum random duration added to each polling interval
+	// so that many watchers don't hit the API at the same time.
+	Jitter time.Duration
+
+	// MaxBackoff caps the delay between polls after consecutive errors. The
+	// delay doubles with every failed poll, starting from the interval.
+	// Defaults to 10 minutes.
+	MaxBackoff time.Duration
+
+	// StateFile is an optional path where the last seen checks are
+	// persisted after every poll. When set, a restarted watcher diffs
+	// against the saved checks instead of starting from scratch, so
+	// transitions are neither replayed nor lost.
+	StateFile string
+
+	// OnError is called whenever a poll fails. The watcher keeps running
+	// and backs off until the next successful poll.
+	OnError func(error)
+}
+
+// WatchEvent is implemented by every event sent by Client.WatchChecks. Use a
+// type switch to find out which event was received.
+type WatchEvent interface {
+	watchEvent()
+}
+
+// StateChangeEvent is sent when the state of a check changes. When the
+// state changed and changed back between two polls OldState and NewState
+// will be equal but Since will have moved.
+type StateChangeEvent struct {
+	// Check holds the check as of the poll that detected the change.
+	Check Check
+
+	// OldState is the state the check was in during the previous poll.
+	OldState string
+
+	// NewState is the current state of the check.
+	NewState string
+
+	// Time is when the change happened, taken from Check.Since when
+	// available.
+	Time time.Time
+}
+
+// Webhook returns the event in the shape of an observery webhook so it can
+// be handled by the same code as real webhooks. Fields only webhooks carry,
+// like ResponseTime, are left empty.
+func (e StateChangeEvent) Webhook() *Webhook {
+	return &Webhook{
+		CheckID:   e.Check.ID,
+		CheckName: e.Check.Name,
+		CheckType: e.Check.Type,
+		State:     e.NewState,
+	}
+}
+
+// CheckAddedEvent is sent when a new check shows up.
+type CheckAddedEvent struct {
+	// Check is the new check.
+	Check Check
+
+	// Time is when the check was first seen.
+	Time time.Time
+}
+
+// CheckRemovedEvent is sent when a check is no longer returned by the API.
+type CheckRemovedEvent struct {
+	// Check is the check as it was last seen.
+	Check Check
+
+	// Time is when the check was found to be missing.
+	Time time.Time
+}
+
+// CheckActivatedEvent is sent when an inactive check becomes active.
+type CheckActivatedEvent struct {
+	// Check is the activated check.
+	Check Check
+
+	// Time is when the activation was seen.
+	Time time.Time
+}
+
+// CheckDeactivatedEvent is sent when an active check becomes inactive.
+type CheckDeactivatedEvent struct {
+	// Check is the deactivated check.
+	Check Check
+
+	// Time is when the deactivation was seen.
+	Time time.Time
+}
+
+func (StateChangeEvent) watchEvent()      {}
+func (CheckAddedEvent) watchEvent()       {}
+func (CheckRemovedEvent) watchEvent()     {}
+func (CheckActivatedEvent) watchEvent()   {}
+func (CheckDeactivatedEvent) watchEvent() {}
+
+// WatchChecks polls Client.ListChecks every interval and sends an event for
+// every difference from the previous poll. The first poll only records the
+// current checks unless a snapshot was loaded from WatchOptions.StateFile.
+// The returned channel is closed once ctx is done.
+func (c *Client) WatchChecks(ctx context.Context, interval time.Duration, opts *WatchOptions) <-chan WatchEvent {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		last, err := loadChecks(opts.StateFile)
+		if err != nil && !os.IsNotExist(err) && opts.OnError != nil {
+			opts.OnError(err)
+		}
+
+		failures := 0
+
+		for {
+			resp, err := c.ListChecks(ctx)
+			if err == nil && !resp.Success {
+				err = errors.New(resp.Reason)
+			}
+
+			if err != nil {
+				failures++
+				if opts.OnError != nil && ctx.Err() == nil {
+					opts.OnError(err)
+				}
+			} else {
+				failures = 0
+				current := make(map[string]Check, len(resp.Checks))
+				for _, check := range resp.Checks {
+					current[check.ID] = check
+				}
+
+				if last != nil {
+					for _, event := range diffChecks(last, current, time.Now()) {
+						select {
+						case events <- event:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+
+				last = current
+				if err := saveChecks(opts.StateFile, last); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+
+			select {
+			case <-time.After(pollDelay(interval, failures, opts)):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// diffChecks returns the events needed to get from prev to next, ordered by
+// check id so the output is deterministic.
+func diffChecks(prev, next map[string]Check, now time.Time) []WatchEvent {
+	var events []WatchEvent
+
+	for _, id := range sortedIDs(next) {
+		check := next[id]
+		old, ok := prev[id]
+		if !ok {
+			events = append(events, CheckAddedEvent{Check: check, Time: now})
+			continue
+		}
+
+		if old.Active != check.Active {
+			if check.Active {
+				events = append(events, CheckActivatedEvent{Check: check, Time: now})
+			} else {
+				events = append(events, CheckDeactivatedEvent{Check: check, Time: now})
+			}
+		}
+
+		if old.State != check.State || !old.Since.Equal(check.Since) {
+			when := check.Since
+			if when.IsZero() {
+				when = now
+			}
+			events = append(events, StateChangeEvent{
+				Check:    check,
+				OldState: old.State,
+				NewState: check.State,
+				Time:     when,
+			})
+		}
+	}
+
+	for _, id := range sortedIDs(prev) {
+		if _, ok := next[id]; !ok {
+			events = append(events, CheckRemovedEvent{Check: prev[id], Time: now})
+		}
+	}
+
+	return events
+}
+
+func sortedIDs(checks map[string]Check) []string {
+	ids := make([]string, 0, len(checks))
+	for id := range checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// pollDelay returns how long to wait before the next poll. Every failure
+// doubles the interval up to opts.MaxBackoff.
+func pollDelay(interval time.Duration, failures int, opts *WatchOptions) time.Duration {
+	delay := interval
+
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = 10 * time.Minute
+	}
+	for i := 0; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if failures > 0 && delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	if opts.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(opts.Jitter)))
+	}
+
+	return delay
+}
+
+func loadChecks(path string) (map[string]Check, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := map[string]Check{}
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// saveChecks replaces the state file atomically so a crash can't leave a
+// truncated state file behind.
+func saveChecks(path string, checks map[string]Check) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(checks)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0600)
+}
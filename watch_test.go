@@ -0,0 +1,140 @@
+package observery
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiffChecks(t *testing.T) {
+	var (
+		now   = time.Now()
+		since = now.Add(-time.Hour)
+	)
+
+	prev := map[string]Check{
+		"a": {ID: "a", Active: true, State: "up", Since: since},
+		"b": {ID: "b", Active: true, State: "up", Since: since},
+		"c": {ID: "c", Active: true, State: "up", Since: since},
+	}
+	next := map[string]Check{
+		"a": {ID: "a", Active: true, State: "down", Since: now},
+		"b": {ID: "b", Active: false, State: "up", Since: since},
+		"d": {ID: "d", Active: true, State: "waiting"},
+	}
+
+	events := diffChecks(prev, next, now)
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events but got %d: %+v\n", len(events), events)
+	}
+
+	if e, ok := events[0].(StateChangeEvent); !ok || e.OldState != "up" || e.NewState != "down" || !e.Time.Equal(now) {
+		t.Fatalf("Expected a state change for a but got %+v\n", events[0])
+	}
+	if e, ok := events[1].(CheckDeactivatedEvent); !ok || e.Check.ID != "b" {
+		t.Fatalf("Expected b to be deactivated but got %+v\n", events[1])
+	}
+	if e, ok := events[2].(CheckAddedEvent); !ok || e.Check.ID != "d" {
+		t.Fatalf("Expected d to be added but got %+v\n", events[2])
+	}
+	if e, ok := events[3].(CheckRemovedEvent); !ok || e.Check.ID != "c" {
+		t.Fatalf("Expected c to be removed but got %+v\n", events[3])
+	}
+
+	if events := diffChecks(next, next, now); len(events) != 0 {
+		t.Fatalf("Expected no events but got %+v\n", events)
+	}
+}
+
+func listBody(state, since string) string {
+	return `{"success": true, "result": [{"id": "1", "name": "api", "type": "http", "state": "` + state + `", "since": "` + since + `", "active": true}]}`
+}
+
+func TestWatchChecks(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		errs []error
+		c    = fakeClient(&fakeAPI{sequence: []string{
+			listBody("up", "2019-10-01T12:00:00"),
+			"error",
+			listBody("down", "2019-10-01T12:05:00"),
+		}})
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := &WatchOptions{OnError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}}
+	event := <-c.WatchChecks(ctx, time.Millisecond, opts)
+	e, ok := event.(StateChangeEvent)
+	if !ok || e.OldState != "up" || e.NewState != "down" || !e.Time.Equal(time.Date(2019, 10, 1, 12, 5, 0, 0, time.UTC)) {
+		t.Fatalf("Expected up to go down but got %#v\n", event)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "connection refused") {
+		t.Fatalf("Expected the failed poll to be reported once but got %v\n", errs)
+	}
+}
+
+func TestWatchChecksStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "observery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checks.json")
+
+	up := listBody("up", "2019-10-01T12:00:00")
+	c := fakeClient(&fakeAPI{sequence: []string{up}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := c.WatchChecks(ctx, time.Millisecond, &WatchOptions{StateFile: path})
+	for i := 0; ; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if i == 500 {
+			t.Fatal("The state file was never written")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	for event := range events {
+		t.Fatalf("Expected no events from an unchanged check but got %#v\n", event)
+	}
+
+	// A restarted watcher neither replays the check as added nor misses
+	// what changed while it was down.
+	c.client = &http.Client{Transport: &fakeAPI{sequence: []string{up, up, listBody("down", "2019-10-01T12:05:00")}}}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event := <-c.WatchChecks(ctx, time.Millisecond, &WatchOptions{StateFile: path})
+	if e, ok := event.(StateChangeEvent); !ok || e.OldState != "up" || e.NewState != "down" {
+		t.Fatalf("Expected only the state change after restarting but got %#v\n", event)
+	}
+}
+
+func TestPollDelay(t *testing.T) {
+	opts := &WatchOptions{MaxBackoff: time.Minute}
+	for failures, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := pollDelay(10*time.Second, failures, opts); got != want {
+			t.Errorf("Expected a delay of %s after %d failures but got %s\n", want, failures, got)
+		}
+	}
+
+	opts.Jitter = time.Second
+	if got := pollDelay(10*time.Second, 0, opts); got < 10*time.Second || got >= 11*time.Second {
+		t.Errorf("Expected up to a second of jitter but got %s\n", got)
+	}
+}
@@ -58,13 +58,9 @@ func (w *Webhook) Decode(r *http.Request) error {
 // goroutine so it doesn't tie up the observery caller in the event that f is a
 // long running task.
 func WebhookHandler(f func(*Webhook, error)) func(w http.ResponseWriter, r *http.Request) {
-	var (
-		hook *Webhook
-		err  error
-	)
-	callback := func(w http.ResponseWriter, r *http.Request) {
-		err = hook.Decode(r)
+	return func(w http.ResponseWriter, r *http.Request) {
+		hook := &Webhook{}
+		err := hook.Decode(r)
+		go f(hook, err)
 	}
-	go f(hook, err)
-	return callback
 }